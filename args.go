@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 )
 
@@ -66,8 +69,32 @@ type Argument interface {
 	Usage() string
 }
 
+// DocGenerationArgument is an interface for arguments whose details are
+// shown in help, documentation and shell completion
+type DocGenerationArgument interface {
+	Argument
+
+	// GetName returns the name of the argument
+	GetName() string
+
+	// GetUsage returns the description of the argument
+	GetUsage() string
+
+	// GetTypeName returns the name of the type of the argument's values
+	GetTypeName() string
+
+	// GetDefaultText returns the default text for this argument
+	GetDefaultText() string
+
+	// Occurrences returns the min and max number of values of the argument,
+	// where a max of -1 means unlimited
+	Occurrences() (min, max int)
+}
+
 type ArgumentBase[T any, C any, VC ValueCreator[T, C]] struct {
 	Name        string // the name of this argument
+	Description string // the description shown in the ARGUMENTS section of help
+	DefaultText string // default text of the argument for usage purposes
 	Value       T      // the default value of this argument
 	Destination *T     // the destination point for this argument
 	Values      *[]T   // all the values of this argument, only if multiple are supported
@@ -75,72 +102,583 @@ type ArgumentBase[T any, C any, VC ValueCreator[T, C]] struct {
 	Min         int    // the min num of occurrences of this argument
 	Max         int    // the max num of occurrences of this argument, set to -1 for unlimited
 	Config      C      // config for this argument similar to Flag Config
+
+	Sources   ValueSourceChain                         // sources to load the value from when it is not given
+	Choices   []T                                      // the values allowed for this argument, any value when empty
+	Validator func(T) error                            // custom function to validate each value of this argument
+	Action    func(context.Context, *Command, T) error // Action callback called for each value of this argument
+
+	// unexported fields for internal use
+	values []T // values parsed in the last run
 }
 
+// Usage returns the synopsis of the argument, e.g. <src>... for one or more
+// values or [<dst>] for an optional value
 func (a *ArgumentBase[T, C, VC]) Usage() string {
 	if a.UsageText != "" {
 		return a.UsageText
 	}
 
-	usageFormat := ""
+	usage := "<" + a.Name + ">"
+	if a.Max != 1 {
+		usage += "..."
+	}
 	if a.Min == 0 {
-		if a.Max == 1 {
-			usageFormat = "[%[1]s]"
-		} else {
-			usageFormat = "[%[1]s ...]"
+		usage = "[" + usage + "]"
+	}
+
+	return usage
+}
+
+// String returns a readable representation of this argument for help output
+func (a *ArgumentBase[T, C, VC]) String() string {
+	return ArgumentStringer(a)
+}
+
+// GetName returns the name of the argument
+func (a *ArgumentBase[T, C, VC]) GetName() string {
+	return a.Name
+}
+
+// GetUsage returns the description of the argument
+func (a *ArgumentBase[T, C, VC]) GetUsage() string {
+	return a.Description
+}
+
+// GetTypeName returns the name of the type of the argument's values
+func (a *ArgumentBase[T, C, VC]) GetTypeName() string {
+	return fmt.Sprintf("%T", a.Value)
+}
+
+// GetDefaultText returns the default text for this argument
+func (a *ArgumentBase[T, C, VC]) GetDefaultText() string {
+	if a.DefaultText != "" {
+		return a.DefaultText
+	}
+	if reflect.ValueOf(&a.Value).Elem().IsZero() {
+		return ""
+	}
+	var vc VC
+	return vc.ToString(a.Value)
+}
+
+// Occurrences returns the min and max number of values of the argument
+func (a *ArgumentBase[T, C, VC]) Occurrences() (int, int) {
+	return a.Min, a.Max
+}
+
+// GetEnvVars returns the env vars among the sources of the argument
+func (a *ArgumentBase[T, C, VC]) GetEnvVars() []string {
+	vals := []string{}
+
+	for _, src := range a.Sources.Chain {
+		if v, ok := src.(*envVarValueSource); ok {
+			vals = append(vals, v.Key)
 		}
-	} else {
-		usageFormat = "%[1]s [%[1]s ...]"
 	}
-	return fmt.Sprintf(usageFormat, a.Name)
+
+	return vals
+}
+
+// Get returns the first value of the argument given in the last run, or
+// its default value when none was given
+func (a *ArgumentBase[T, C, VC]) Get() T {
+	if len(a.values) > 0 {
+		return a.values[0]
+	}
+	return a.Value
+}
+
+// GetValues returns the values of the argument given in the last run
+func (a *ArgumentBase[T, C, VC]) GetValues() []T {
+	return append([]T{}, a.values...)
+}
+
+// IsSet returns whether a value was given for the argument in the last run
+func (a *ArgumentBase[T, C, VC]) IsSet() bool {
+	return len(a.values) > 0
+}
+
+func (a *ArgumentBase[T, C, VC]) getValue() any {
+	return a.Get()
+}
+
+func (a *ArgumentBase[T, C, VC]) getValues() any {
+	return a.GetValues()
 }
 
 func (a *ArgumentBase[T, C, VC]) Parse(s []string) ([]string, error) {
+	return a.parseContext(context.Background(), s)
+}
+
+// parseContext parses the values of the argument from s, looking up its
+// Sources in the given context when no value is given
+func (a *ArgumentBase[T, C, VC]) parseContext(ctx context.Context, s []string) ([]string, error) {
 	tracef("calling arg%[1] parse with args %[2]", &a.Name, s)
+	a.values = nil
 	if a.Max == 0 {
-		fmt.Printf("WARNING args %s has max 0, not parsing argument", a.Name)
-		return s, nil
+		return s, &ArgumentDefinitionError{Name: a.Name, Msg: "max is 0"}
 	}
 	if a.Max != -1 && a.Min > a.Max {
-		fmt.Printf("WARNING args %s has min[%d] > max[%d], not parsing argument", a.Name, a.Min, a.Max)
-		return s, nil
+		return s, &ArgumentDefinitionError{Name: a.Name, Msg: fmt.Sprintf("min %[1]d is greater than max %[2]d", a.Min, a.Max)}
+	}
+
+	foldCase := false
+	if cmd := commandFromContext(ctx); cmd != nil {
+		foldCase = cmd.Root().CaseInsensitive
 	}
 
 	count := 0
-	var vc VC
-	var t T
-	value := vc.Create(a.Value, &t, a.Config)
 	values := []T{}
 
 	for _, arg := range s {
-		if err := value.Set(arg); err != nil {
+		v, err := a.parseValue(arg, foldCase)
+		if err != nil {
 			return s, err
 		}
-		values = append(values, value.Get().(T))
+		values = append(values, v)
 		count++
-		if count >= a.Max {
+		if a.Max != -1 && count >= a.Max {
 			break
 		}
 	}
-	if count < a.Min {
-		return s, fmt.Errorf("sufficient count of arg %s not provided, given %d expected %d", a.Name, count, a.Min)
+
+	if count == 0 && len(a.Sources.Chain) > 0 {
+		val, src, found, err := a.Sources.LookupWithSourceContext(ctx)
+		if err != nil {
+			return s, fmt.Errorf("could not look up argument %[1]s from %[2]s: %[3]w", a.Name, src, err)
+		}
+		if found {
+			tracef("using value %[1]q of argument %[2]q from %[3]s", val, a.Name, src)
+			v, err := a.parseValue(val, foldCase)
+			if err != nil {
+				return s, err
+			}
+			values = append(values, v)
+		}
 	}
 
+	if len(values) < a.Min {
+		return s, &ArgumentCountError{Name: a.Name, Given: len(values), Min: a.Min}
+	}
+
+	a.values = values
 	if a.Values == nil {
 		a.Values = &values
 	} else {
 		*a.Values = values
 	}
 
-	if a.Max == 1 && a.Destination != nil {
+	if a.Max == 1 && a.Destination != nil && len(values) > 0 {
 		*a.Destination = values[0]
 	}
 	return s[count:], nil
 }
 
+// parseValue parses raw into a value created by the ValueCreator of the
+// argument and checks it against the Choices and the Validator. With
+// foldCase string choices are matched regardless of case and the value is
+// replaced by the matching choice.
+func (a *ArgumentBase[T, C, VC]) parseValue(raw string, foldCase bool) (T, error) {
+	var (
+		vc VC
+		t  T
+	)
+	value := vc.Create(a.Value, &t, a.Config)
+	if err := value.Set(raw); err != nil {
+		return t, &ArgumentValueError{Name: a.Name, Value: raw, Err: err}
+	}
+
+	v, ok := value.Get().(T)
+	if !ok {
+		return t, &typeError[T]{other: value.Get()}
+	}
+
+	if len(a.Choices) > 0 {
+		choice, ok := a.matchChoice(v, foldCase)
+		if !ok {
+			choices := make([]string, 0, len(a.Choices))
+			for _, choice := range a.Choices {
+				choices = append(choices, fmt.Sprint(choice))
+			}
+			return t, &ArgumentValueError{Name: a.Name, Value: raw, Err: &errNotAChoice{choices: choices, provided: raw}}
+		}
+		v = choice
+	}
+
+	if a.Validator != nil {
+		if err := a.Validator(v); err != nil {
+			return t, &ArgumentValueError{Name: a.Name, Value: raw, Err: err}
+		}
+	}
+
+	return v, nil
+}
+
+// matchChoice returns the choice of the argument equal to v, ignoring the
+// case of string choices with foldCase
+func (a *ArgumentBase[T, C, VC]) matchChoice(v T, foldCase bool) (T, bool) {
+	for _, choice := range a.Choices {
+		if reflect.DeepEqual(v, choice) {
+			return choice, true
+		}
+	}
+
+	if s, ok := any(v).(string); ok && foldCase {
+		for _, choice := range a.Choices {
+			if strings.EqualFold(s, any(choice).(string)) {
+				return choice, true
+			}
+		}
+	}
+
+	var t T
+	return t, false
+}
+
+// stringChoices returns the choices of the argument when they are strings
+func (a *ArgumentBase[T, C, VC]) stringChoices() []string {
+	choices, _ := any(a.Choices).([]string)
+	return choices
+}
+
+// runAction calls the Action of the argument with each of its values
+func (a *ArgumentBase[T, C, VC]) runAction(ctx context.Context, cmd *Command) error {
+	if a.Action == nil {
+		return nil
+	}
+
+	for _, v := range a.values {
+		if err := a.Action(ctx, cmd, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type BoolArg = ArgumentBase[bool, BoolConfig, boolValue]
+type DurationArg = ArgumentBase[time.Duration, NoConfig, durationValue]
 type FloatArg = ArgumentBase[float64, NoConfig, floatValue]
+type FloatSliceArg = ArgumentBase[[]float64, NoConfig, FloatSlice]
 type IntArg = ArgumentBase[int64, IntegerConfig, intValue]
+type IntSliceArg = ArgumentBase[[]int64, IntegerConfig, IntSlice]
 type StringArg = ArgumentBase[string, StringConfig, stringValue]
 type StringMapArg = ArgumentBase[map[string]string, StringConfig, StringMap]
+type StringSliceArg = ArgumentBase[[]string, StringConfig, StringSlice]
 type TimestampArg = ArgumentBase[time.Time, TimestampConfig, timestampValue]
 type UintArg = ArgumentBase[uint64, IntegerConfig, uintValue]
+type UintSliceArg = ArgumentBase[[]uint64, IntegerConfig, UintSlice]
+
+// ArgumentStringer converts an argument definition to a string. This is
+// used by help to display an argument in the ARGUMENTS section.
+var ArgumentStringer ArgumentStringFunc = stringifyArgument
+
+func stringifyArgument(arg DocGenerationArgument) string {
+	name := "<" + arg.GetName() + ">"
+	if _, max := arg.Occurrences(); max != 1 {
+		name += "..."
+	}
+
+	details := []string{"type: " + arg.GetTypeName()}
+	if min, _ := arg.Occurrences(); min == 0 {
+		if s := arg.GetDefaultText(); s != "" {
+			details = append(details, "default: "+s)
+		}
+	}
+
+	usage := fmt.Sprintf("(%[1]s)", strings.Join(details, ", "))
+	if s := arg.GetUsage(); s != "" {
+		usage = s + " " + usage
+	}
+
+	return name + "\t" + usage
+}
+
+// occurrencesArgument is an interface for arguments which declare how many
+// values they take
+type occurrencesArgument interface {
+	Occurrences() (min, max int)
+}
+
+// contextArgument is an interface for arguments which look up values in
+// the context of the command when parsed
+type contextArgument interface {
+	parseContext(ctx context.Context, s []string) ([]string, error)
+}
+
+// envVarArgument is an interface for arguments with env var sources
+type envVarArgument interface {
+	GetEnvVars() []string
+}
+
+// actionableArgument is an interface for arguments with an Action
+type actionableArgument interface {
+	runAction(ctx context.Context, cmd *Command) error
+}
+
+// runArgumentActions calls the Action of every argument of the command
+func (cmd *Command) runArgumentActions(ctx context.Context) error {
+	for _, arg := range cmd.Arguments {
+		if aa, ok := arg.(actionableArgument); ok {
+			if err := aa.runAction(ctx, cmd); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseArguments parses the given values into the Arguments of the command
+// and returns the values left over. Every argument takes as many values as
+// it can while leaving enough for the minimum of the arguments following
+// it, so that e.g. <src>... <dst> leaves the last value to dst.
+func (cmd *Command) parseArguments(ctx context.Context, rargs []string) ([]string, error) {
+	tracef("calling argparse with %[1]v", rargs)
+
+	reserved := make([]int, len(cmd.Arguments)+1)
+	for i := len(cmd.Arguments) - 1; i >= 0; i-- {
+		reserved[i] = reserved[i+1]
+		if oa, ok := cmd.Arguments[i].(occurrencesArgument); ok {
+			if lo, _ := oa.Occurrences(); lo > 0 {
+				reserved[i] += lo
+			}
+		}
+	}
+
+	for i, arg := range cmd.Arguments {
+		limit := len(rargs)
+		if oa, ok := arg.(occurrencesArgument); ok {
+			lo, hi := oa.Occurrences()
+			if limit -= reserved[i+1]; limit < lo {
+				limit = lo
+			}
+			if hi >= 0 && limit > hi {
+				limit = hi
+			}
+			if limit > len(rargs) {
+				limit = len(rargs)
+			}
+		}
+
+		var (
+			rest []string
+			err  error
+		)
+		if ca, ok := arg.(contextArgument); ok {
+			rest, err = ca.parseContext(ctx, rargs[:limit])
+		} else {
+			rest, err = arg.Parse(rargs[:limit])
+		}
+		if err != nil {
+			return rargs, err
+		}
+		rargs = append(append([]string{}, rest...), rargs[limit:]...)
+	}
+
+	return rargs, nil
+}
+
+// valuedArgument is an interface to access the parsed values of an argument
+type valuedArgument interface {
+	Argument
+	GetName() string
+	IsSet() bool
+	getValue() any
+	getValues() any
+}
+
+// lookupArgument returns the argument of the command with the given name
+func (cmd *Command) lookupArgument(name string) valuedArgument {
+	for _, arg := range cmd.Arguments {
+		if va, ok := arg.(valuedArgument); ok && va.GetName() == name {
+			return va
+		}
+	}
+
+	tracef("argument NOT found for name %[1]q (cmd=%[2]q)", name, cmd.Name)
+	cmd.onInvalidArgument(context.TODO(), name)
+	return nil
+}
+
+func (cmd *Command) onInvalidArgument(ctx context.Context, name string) {
+	for cmd != nil {
+		if cmd.InvalidArgumentAccessHandler != nil {
+			cmd.InvalidArgumentAccessHandler(ctx, cmd, name)
+			break
+		}
+		cmd = cmd.parent
+	}
+}
+
+// ArgValue returns the first value of the argument with the given name, or
+// its default when none was given. It reads arguments of any type, including
+// those defined with a custom ValueCreator:
+//
+//	cli.ArgValue[net.IP](cmd, "addr")
+func ArgValue[T any](cmd *Command, name string) T {
+	if arg := cmd.lookupArgument(name); arg != nil {
+		if v, ok := arg.getValue().(T); ok {
+			return v
+		}
+	}
+
+	var t T
+	return t
+}
+
+// ArgValues returns the values of the argument with the given name
+func ArgValues[T any](cmd *Command, name string) []T {
+	if arg := cmd.lookupArgument(name); arg != nil {
+		if v, ok := arg.getValues().([]T); ok {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// ArgSet returns whether a value was given for the argument with the given
+// name
+func (cmd *Command) ArgSet(name string) bool {
+	if arg := cmd.lookupArgument(name); arg != nil {
+		return arg.IsSet()
+	}
+	return false
+}
+
+// BoolArg returns the first value of the bool argument with the given name
+func (cmd *Command) BoolArg(name string) bool {
+	return ArgValue[bool](cmd, name)
+}
+
+// BoolArgs returns the values of the bool argument with the given name
+func (cmd *Command) BoolArgs(name string) []bool {
+	return ArgValues[bool](cmd, name)
+}
+
+// DurationArg returns the first value of the duration argument with the
+// given name
+func (cmd *Command) DurationArg(name string) time.Duration {
+	return ArgValue[time.Duration](cmd, name)
+}
+
+// DurationArgs returns the values of the duration argument with the given
+// name
+func (cmd *Command) DurationArgs(name string) []time.Duration {
+	return ArgValues[time.Duration](cmd, name)
+}
+
+// FloatArg returns the first value of the float argument with the given name
+func (cmd *Command) FloatArg(name string) float64 {
+	return ArgValue[float64](cmd, name)
+}
+
+// FloatArgs returns the values of the float argument with the given name
+func (cmd *Command) FloatArgs(name string) []float64 {
+	return ArgValues[float64](cmd, name)
+}
+
+// FloatSliceArg returns the first value of the float slice argument with
+// the given name
+func (cmd *Command) FloatSliceArg(name string) []float64 {
+	return ArgValue[[]float64](cmd, name)
+}
+
+// FloatSliceArgs returns the values of the float slice argument with the
+// given name
+func (cmd *Command) FloatSliceArgs(name string) [][]float64 {
+	return ArgValues[[]float64](cmd, name)
+}
+
+// IntArg returns the first value of the int argument with the given name
+func (cmd *Command) IntArg(name string) int64 {
+	return ArgValue[int64](cmd, name)
+}
+
+// IntArgs returns the values of the int argument with the given name
+func (cmd *Command) IntArgs(name string) []int64 {
+	return ArgValues[int64](cmd, name)
+}
+
+// IntSliceArg returns the first value of the int slice argument with the
+// given name
+func (cmd *Command) IntSliceArg(name string) []int64 {
+	return ArgValue[[]int64](cmd, name)
+}
+
+// IntSliceArgs returns the values of the int slice argument with the given
+// name
+func (cmd *Command) IntSliceArgs(name string) [][]int64 {
+	return ArgValues[[]int64](cmd, name)
+}
+
+// StringArg returns the first value of the string argument with the given
+// name
+func (cmd *Command) StringArg(name string) string {
+	return ArgValue[string](cmd, name)
+}
+
+// StringArgs returns the values of the string argument with the given name
+func (cmd *Command) StringArgs(name string) []string {
+	return ArgValues[string](cmd, name)
+}
+
+// StringMapArg returns the first value of the string map argument with the
+// given name
+func (cmd *Command) StringMapArg(name string) map[string]string {
+	return ArgValue[map[string]string](cmd, name)
+}
+
+// StringMapArgs returns the values of the string map argument with the
+// given name
+func (cmd *Command) StringMapArgs(name string) []map[string]string {
+	return ArgValues[map[string]string](cmd, name)
+}
+
+// StringSliceArg returns the first value of the string slice argument with
+// the given name
+func (cmd *Command) StringSliceArg(name string) []string {
+	return ArgValue[[]string](cmd, name)
+}
+
+// StringSliceArgs returns the values of the string slice argument with the
+// given name
+func (cmd *Command) StringSliceArgs(name string) [][]string {
+	return ArgValues[[]string](cmd, name)
+}
+
+// TimestampArg returns the first value of the timestamp argument with the
+// given name
+func (cmd *Command) TimestampArg(name string) time.Time {
+	return ArgValue[time.Time](cmd, name)
+}
+
+// TimestampArgs returns the values of the timestamp argument with the given
+// name
+func (cmd *Command) TimestampArgs(name string) []time.Time {
+	return ArgValues[time.Time](cmd, name)
+}
+
+// UintArg returns the first value of the uint argument with the given name
+func (cmd *Command) UintArg(name string) uint64 {
+	return ArgValue[uint64](cmd, name)
+}
+
+// UintArgs returns the values of the uint argument with the given name
+func (cmd *Command) UintArgs(name string) []uint64 {
+	return ArgValues[uint64](cmd, name)
+}
+
+// UintSliceArg returns the first value of the uint slice argument with the
+// given name
+func (cmd *Command) UintSliceArg(name string) []uint64 {
+	return ArgValue[[]uint64](cmd, name)
+}
+
+// UintSliceArgs returns the values of the uint slice argument with the
+// given name
+func (cmd *Command) UintSliceArgs(name string) [][]uint64 {
+	return ArgValues[[]uint64](cmd, name)
+}
@@ -1,8 +1,11 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -111,37 +114,37 @@ func TestArgsUsage(t *testing.T) {
 			name:     "optional",
 			min:      0,
 			max:      1,
-			expected: "[ia]",
+			expected: "[<ia>]",
 		},
 		{
 			name:     "zero or more",
 			min:      0,
 			max:      2,
-			expected: "[ia ...]",
+			expected: "[<ia>...]",
 		},
 		{
 			name:     "one",
 			min:      1,
 			max:      1,
-			expected: "ia [ia ...]",
+			expected: "<ia>",
 		},
 		{
 			name:     "many",
 			min:      2,
 			max:      1,
-			expected: "ia [ia ...]",
+			expected: "<ia>",
 		},
 		{
 			name:     "many2",
 			min:      2,
 			max:      0,
-			expected: "ia [ia ...]",
+			expected: "<ia>...",
 		},
 		{
 			name:     "unlimited",
 			min:      2,
 			max:      -1,
-			expected: "ia [ia ...]",
+			expected: "<ia>...",
 		},
 	}
 	for _, test := range tests {
@@ -151,3 +154,363 @@ func TestArgsUsage(t *testing.T) {
 		})
 	}
 }
+
+func TestArgumentsHelp(t *testing.T) {
+	out := &bytes.Buffer{}
+	cmd := &Command{
+		Name:   "app",
+		Writer: out,
+		Commands: []*Command{
+			{
+				Name:  "cp",
+				Usage: "copy files",
+				Arguments: []Argument{
+					&StringArg{Name: "src", Description: "files to copy", Min: 1, Max: -1},
+					&StringArg{Name: "dst", Description: "destination directory", Min: 1, Max: 1},
+				},
+				Action: func(context.Context, *Command) error { return nil },
+			},
+			{
+				Name: "retry",
+				Arguments: []Argument{
+					&IntArg{Name: "count", Value: 3, Max: 1},
+					&StringArg{Name: "name", Description: "name of the job", Value: "all", DefaultText: "every job", Max: 1},
+				},
+				Action: func(context.Context, *Command) error { return nil },
+			},
+		},
+	}
+
+	require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "cp", "--help"}))
+	require.Equal(t, `NAME:
+   app cp - copy files
+
+USAGE:
+   app cp [command [command options]] <src>... <dst>
+
+ARGUMENTS:
+   <src>...  files to copy (type: string)
+   <dst>     destination directory (type: string)
+
+OPTIONS:
+   --help, -h  show help (default: false)
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "retry", "--help"}))
+	require.Contains(t, out.String(), "app retry [command [command options]] [<count>] [<name>]")
+	require.Contains(t, out.String(), `ARGUMENTS:
+   <count>  (type: int64, default: 3)
+   <name>   name of the job (type: string, default: every job)
+`)
+
+	var arg DocGenerationArgument = &StringArg{Name: "src", Min: 1, Max: -1}
+	min, max := arg.Occurrences()
+	require.Equal(t, "src", arg.GetName())
+	require.Equal(t, "string", arg.GetTypeName())
+	require.Equal(t, []int{1, -1}, []int{min, max})
+}
+
+func TestArgumentAccessors(t *testing.T) {
+	var (
+		invalid []string
+		src     []string
+		dst     string
+		ids     []int64
+		force   float64
+		srcSet  bool
+		noteSet bool
+	)
+
+	cmd := &Command{
+		Name: "app",
+		InvalidArgumentAccessHandler: func(_ context.Context, _ *Command, name string) {
+			invalid = append(invalid, name)
+		},
+		Commands: []*Command{
+			{
+				Name: "cp",
+				Arguments: []Argument{
+					&StringArg{Name: "src", Min: 1, Max: 2},
+					&StringArg{Name: "dst", Min: 1, Max: 1},
+					&IntArg{Name: "ids", Max: 3},
+					&StringArg{Name: "note", Value: "none", Max: 1},
+				},
+				Action: func(_ context.Context, cmd *Command) error {
+					src = cmd.StringArgs("src")
+					dst = cmd.StringArg("dst")
+					ids = cmd.IntArgs("ids")
+					force = cmd.FloatArg("force")
+					srcSet = cmd.ArgSet("src")
+					noteSet = cmd.ArgSet("note")
+					require.Equal(t, "none", cmd.StringArg("note"))
+					require.Equal(t, "", cmd.StringArg("ids"), "type mismatch")
+					return nil
+				},
+			},
+		},
+	}
+
+	require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "cp", "a", "b", "c", "1", "2"}))
+	require.Equal(t, []string{"a", "b"}, src)
+	require.Equal(t, "c", dst)
+	require.Equal(t, []int64{1, 2}, ids)
+	require.Zero(t, force)
+	require.True(t, srcSet)
+	require.False(t, noteSet)
+	require.Equal(t, []string{"force"}, invalid)
+}
+
+func TestArgumentsTrailingFixed(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected map[string][]string
+		rest     []string
+		err      string
+	}{
+		{
+			name:     "one source",
+			args:     []string{"a", "dest"},
+			expected: map[string][]string{"src": {"a"}, "dst": {"dest"}},
+		},
+		{
+			name:     "many sources",
+			args:     []string{"a", "b", "c", "dest"},
+			expected: map[string][]string{"src": {"a", "b", "c"}, "dst": {"dest"}},
+		},
+		{
+			name: "missing destination",
+			args: []string{"a"},
+			err:  "sufficient count of arg dst not provided, given 0 expected 1",
+		},
+		{
+			name: "missing source",
+			args: []string{},
+			err:  "sufficient count of arg src not provided, given 0 expected 1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := &Command{
+				Name: "cp",
+				Arguments: []Argument{
+					&StringArg{Name: "src", Min: 1, Max: -1},
+					&StringArg{Name: "dst", Min: 1, Max: 1},
+				},
+				Action: func(context.Context, *Command) error { return nil },
+			}
+
+			err := cmd.Run(buildTestContext(t), append([]string{"cp"}, test.args...))
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			for name, values := range test.expected {
+				require.Equal(t, values, cmd.StringArgs(name), name)
+			}
+		})
+	}
+
+	t.Run("optional middle", func(t *testing.T) {
+		cmd := &Command{
+			Name: "tag",
+			Arguments: []Argument{
+				&StringArg{Name: "repo", Min: 1, Max: 1},
+				&StringArg{Name: "ref", Max: 1},
+				&StringArg{Name: "tags", Min: 2, Max: 3},
+			},
+			Action: func(context.Context, *Command) error { return nil },
+		}
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"tag", "r", "t1", "t2"}))
+		require.Equal(t, "r", cmd.StringArg("repo"))
+		require.False(t, cmd.ArgSet("ref"))
+		require.Equal(t, []string{"t1", "t2"}, cmd.StringArgs("tags"))
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"tag", "r", "main", "t1", "t2", "t3", "extra"}))
+		require.Equal(t, "main", cmd.StringArg("ref"))
+		require.Equal(t, []string{"t1", "t2", "t3"}, cmd.StringArgs("tags"))
+		require.Equal(t, []string{"extra"}, cmd.Args().Slice())
+	})
+}
+
+func TestArgumentValidation(t *testing.T) {
+	run := func(arg Argument, args ...string) (*Command, error) {
+		cmd := &Command{
+			Name:      "foo",
+			Env:       map[string]string{"FOO_LEVEL": "warn"},
+			Arguments: []Argument{arg},
+			Action:    func(context.Context, *Command) error { return nil },
+		}
+		return cmd, cmd.Run(buildTestContext(t), append([]string{"foo"}, args...))
+	}
+
+	t.Run("max zero", func(t *testing.T) {
+		_, err := run(&StringArg{Name: "level", Max: 0}, "info")
+		var defErr *ArgumentDefinitionError
+		require.ErrorAs(t, err, &defErr)
+		require.EqualError(t, err, "invalid definition of argument level: max is 0")
+	})
+
+	t.Run("min greater than max", func(t *testing.T) {
+		_, err := run(&StringArg{Name: "level", Min: 2, Max: 1}, "info")
+		require.EqualError(t, err, "invalid definition of argument level: min 2 is greater than max 1")
+	})
+
+	t.Run("count", func(t *testing.T) {
+		_, err := run(&StringArg{Name: "level", Min: 2, Max: 2}, "info")
+		var countErr *ArgumentCountError
+		require.ErrorAs(t, err, &countErr)
+		require.Equal(t, &ArgumentCountError{Name: "level", Given: 1, Min: 2}, countErr)
+	})
+
+	t.Run("choices", func(t *testing.T) {
+		arg := &StringArg{Name: "level", Max: 1, Choices: []string{"debug", "info", "warn"}}
+
+		_, err := run(arg, "inf")
+		var valueErr *ArgumentValueError
+		require.ErrorAs(t, err, &valueErr)
+		require.Equal(t, "inf", valueErr.Value)
+		require.EqualError(t, err, `invalid value "inf" for argument level: must be one of "debug", "info", "warn". Did you mean "info"?`)
+
+		cmd, err := run(arg, "debug")
+		require.NoError(t, err)
+		require.Equal(t, "debug", cmd.StringArg("level"))
+	})
+
+	t.Run("validator", func(t *testing.T) {
+		errOdd := errors.New("must be even")
+		arg := &IntArg{
+			Name: "n",
+			Max:  -1,
+			Validator: func(n int64) error {
+				if n%2 != 0 {
+					return errOdd
+				}
+				return nil
+			},
+		}
+
+		_, err := run(arg, "2", "3")
+		require.ErrorIs(t, err, errOdd)
+		require.EqualError(t, err, `invalid value "3" for argument n: must be even`)
+
+		_, err = run(arg, "x")
+		require.ErrorContains(t, err, `invalid value "x" for argument n: `)
+	})
+
+	t.Run("sources", func(t *testing.T) {
+		arg := &StringArg{Name: "level", Min: 1, Max: 1, Sources: EnvVars("FOO_LEVEL"), Choices: []string{"info", "warn"}}
+
+		cmd, err := run(arg)
+		require.NoError(t, err)
+		require.Equal(t, "warn", cmd.StringArg("level"))
+		require.True(t, cmd.ArgSet("level"))
+
+		cmd, err = run(arg, "info")
+		require.NoError(t, err)
+		require.Equal(t, "info", cmd.StringArg("level"))
+
+		_, err = run(&StringArg{Name: "level", Min: 1, Max: 1, Sources: EnvVars("FOO_MISSING")})
+		require.EqualError(t, err, "sufficient count of arg level not provided, given 0 expected 1")
+	})
+
+	t.Run("action", func(t *testing.T) {
+		var seen []string
+		arg := &StringArg{
+			Name: "files",
+			Max:  -1,
+			Action: func(_ context.Context, cmd *Command, v string) error {
+				require.Equal(t, "foo", cmd.Name)
+				seen = append(seen, v)
+				return nil
+			},
+		}
+
+		_, err := run(arg, "a", "b")
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b"}, seen)
+
+		seen = nil
+		_, err = run(arg)
+		require.NoError(t, err)
+		require.Empty(t, seen)
+	})
+}
+
+func TestArgumentTypes(t *testing.T) {
+	cmd := &Command{
+		Name: "foo",
+		Arguments: []Argument{
+			&BoolArg{Name: "force", Min: 1, Max: 1},
+			&DurationArg{Name: "timeout", Min: 1, Max: 1},
+			&IntSliceArg{Name: "ports", Min: 1, Max: 2},
+			&StringSliceArg{Name: "tags", Min: 1, Max: 1},
+			&FloatSliceArg{Name: "weights", Min: 1, Max: 1},
+			&UintSliceArg{Name: "ids", Min: 1, Max: 1},
+		},
+		Action: func(context.Context, *Command) error { return nil },
+	}
+
+	require.NoError(t, cmd.Run(buildTestContext(t), []string{"foo", "true", "1m30s", "80,443", "8080", "a,b", "0.5,1.5", "1,2"}))
+	require.True(t, cmd.BoolArg("force"))
+	require.Equal(t, 90*time.Second, cmd.DurationArg("timeout"))
+	require.Equal(t, []int64{80, 443}, cmd.IntSliceArg("ports"))
+	require.Equal(t, [][]int64{{80, 443}, {8080}}, cmd.IntSliceArgs("ports"))
+	require.Equal(t, []string{"a", "b"}, cmd.StringSliceArg("tags"))
+	require.Equal(t, []float64{0.5, 1.5}, cmd.FloatSliceArg("weights"))
+	require.Equal(t, []uint64{1, 2}, cmd.UintSliceArg("ids"))
+
+	err := cmd.Run(buildTestContext(t), []string{"foo", "maybe", "1m", "80", "a", "1", "1"})
+	require.ErrorContains(t, err, `invalid value "maybe" for argument force: `)
+}
+
+// upperValue is a custom ValueCreator for upper-cased strings
+type upperValue struct {
+	dest *string
+}
+
+func (v upperValue) Create(val string, p *string, _ NoConfig) Value {
+	*p = val
+	return &upperValue{dest: p}
+}
+
+func (v upperValue) ToString(val string) string {
+	return fmt.Sprintf("%q", val)
+}
+
+func (v *upperValue) Set(s string) error {
+	*v.dest = strings.ToUpper(s)
+	return nil
+}
+
+func (v *upperValue) Get() any { return *v.dest }
+
+func (v *upperValue) String() string {
+	if v.dest == nil {
+		return ""
+	}
+	return *v.dest
+}
+
+func TestArgumentCustomValueCreator(t *testing.T) {
+	cmd := &Command{
+		Name: "foo",
+		Arguments: []Argument{
+			&ArgumentBase[string, NoConfig, upperValue]{Name: "names", Max: -1, Value: "NOBODY"},
+		},
+		Action: func(context.Context, *Command) error { return nil },
+	}
+
+	require.NoError(t, cmd.Run(buildTestContext(t), []string{"foo"}))
+	require.Equal(t, "NOBODY", ArgValue[string](cmd, "names"))
+
+	require.NoError(t, cmd.Run(buildTestContext(t), []string{"foo", "alice", "bob"}))
+	require.Equal(t, "ALICE", ArgValue[string](cmd, "names"))
+	require.Equal(t, []string{"ALICE", "BOB"}, ArgValues[string](cmd, "names"))
+	require.Equal(t, []string{"ALICE", "BOB"}, cmd.StringArgs("names"))
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hasPrefix returns whether s starts with prefix, ignoring case when
+// foldCase is set
+func hasPrefix(s, prefix string, foldCase bool) bool {
+	if !foldCase {
+		return strings.HasPrefix(s, prefix)
+	}
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// choicesArgument is an interface for arguments whose choices are strings
+// and can be matched regardless of case
+type choicesArgument interface {
+	GetName() string
+	stringChoices() []string
+}
+
+// caseCollisions records the names seen in one scope by their case folded
+// form, along with the definition which owns them
+type caseCollisions struct {
+	kind  string
+	scope string
+	names map[string]caseName
+}
+
+type caseName struct {
+	name  string
+	owner any
+}
+
+func newCaseCollisions(kind, scope string) *caseCollisions {
+	return &caseCollisions{kind: kind, scope: scope, names: map[string]caseName{}}
+}
+
+// add records the name of the given owner and returns an error when another
+// owner has a name equal to it regardless of case
+func (c *caseCollisions) add(name string, owner any) error {
+	key := strings.ToLower(name)
+
+	if seen, ok := c.names[key]; ok && seen.owner != owner {
+		return fmt.Errorf(
+			"%[1]s %[2]q and %[3]q of %[4]s collide when case is ignored",
+			c.kind, seen.name, name, c.scope,
+		)
+	}
+
+	c.names[key] = caseName{name: name, owner: owner}
+	return nil
+}
+
+// checkCaseCollisions returns an error when the names of two commands or
+// flags, or two choices of an argument, which are visible to the same
+// command are equal regardless of case
+func (cmd *Command) checkCaseCollisions() error {
+	tracef("checking names colliding regardless of case (cmd=%[1]q)", cmd.Name)
+
+	scope := fmt.Sprintf("command %[1]q", cmd.FullName())
+
+	commands := newCaseCollisions("commands", scope)
+	for _, subCmd := range cmd.Commands {
+		for _, name := range subCmd.Names() {
+			if err := commands.add(name, subCmd); err != nil {
+				return err
+			}
+		}
+	}
+
+	flags := newCaseCollisions("flags", scope)
+	for pCmd := cmd; pCmd != nil; pCmd = pCmd.parent {
+		for _, fl := range pCmd.allFlags() {
+			if pCmd != cmd {
+				if pfl, ok := fl.(PersistentFlag); !ok || !pfl.IsPersistent() {
+					continue
+				}
+			}
+
+			for _, name := range fl.Names() {
+				if err := flags.add(name, fl); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, arg := range cmd.Arguments {
+		ca, ok := arg.(choicesArgument)
+		if !ok {
+			continue
+		}
+
+		choices := newCaseCollisions("choices", fmt.Sprintf("argument %[1]s of %[2]s", ca.GetName(), scope))
+		for i, choice := range ca.stringChoices() {
+			if err := choices.add(choice, i); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, subCmd := range cmd.Commands {
+		if err := subCmd.checkCaseCollisions(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
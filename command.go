@@ -1,17 +1,16 @@
 package cli
 
 import (
-	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
-	"unicode"
 )
 
 const (
@@ -56,6 +55,40 @@ type Command struct {
 	HideHelpCommand bool
 	// Boolean to hide built-in version flag and the VERSION section of help
 	HideVersion bool
+	// Boolean to enable the built-in --debug-flags flag which prints the
+	// effective value of every flag and where it came from
+	// applicable to root command only
+	EnableDebugFlags bool
+	// Boolean to enable the built-in --print-config flag which prints the
+	// effective configuration as JSON, dotenv lines or command line arguments
+	// applicable to root command only
+	EnablePrintConfig bool
+	// Boolean to enable loading flag values from configuration files and the
+	// built-in --config flag. Flags are bound to the key made of the
+	// subcommand path and the flag name, e.g. "serve.port". Config values
+	// have lower precedence than environment variables and the command line.
+	// applicable to root command only
+	EnableConfigFile bool
+	// Configuration files to load when --config is not given, in order of
+	// precedence. All existing files are merged, missing files are skipped.
+	// The parser is picked by file extension from ConfigFileParsers.
+	// applicable to root command only
+	ConfigPaths []string
+	// Boolean to enable named profiles in the config files and the built-in
+	// --profile flag. A profile is defined under the "profiles.NAME" key and
+	// may extend another profile by naming it in "profiles.NAME.extends".
+	// Requires EnableConfigFile.
+	// applicable to root command only
+	EnableProfiles bool
+	// Environment variable selecting the profile when --profile is not given,
+	// defaults to EnvPrefix followed by _PROFILE
+	// applicable to root command only
+	ProfileEnvVar string
+	// Boolean to enable the built-in config command with the get, set,
+	// unset and list subcommands. Values are written to the file given with
+	// --config or to the first of ConfigPaths. Requires EnableConfigFile.
+	// applicable to root command only
+	EnableConfigCommand bool
 	// Boolean to enable shell completion commands
 	EnableShellCompletion bool
 	// Shell Completion generation command name
@@ -76,6 +109,8 @@ type Command struct {
 	OnUsageError OnUsageErrorFunc
 	// Execute this function when an invalid flag is accessed from the context
 	InvalidFlagAccessHandler InvalidFlagAccessFunc
+	// Execute this function when an undeclared argument is accessed by name
+	InvalidArgumentAccessHandler InvalidArgumentAccessFunc
 	// Boolean to hide this command from help or completion
 	Hidden bool
 	// List of all authors who contributed (string or fmt.Stringer)
@@ -115,21 +150,63 @@ type Command struct {
 	AllowExtFlags bool
 	// Treat all flags as normal arguments if true
 	SkipFlagParsing bool
+	// Boolean to allow flags anywhere before "--" instead of only before the
+	// first positional argument, as GNU tools do. It is inherited by
+	// subcommands. i.e. foobar deploy web --force
+	PermuteFlags bool
+	// FlagParser selects how flags are parsed. It is only read from the root
+	// command and applies to all its subcommands.
+	FlagParser FlagParserMode
 	// CustomHelpTemplate the text template for the command help topic.
 	// cli.go uses text/template to render templates. You can
 	// render custom help text by setting this variable.
 	CustomHelpTemplate string
 	// Use longest prefix match for commands
 	PrefixMatchCommands bool
+	// Boolean to match the names and aliases of commands and flags and the
+	// choices of arguments regardless of case. It is only read from the root
+	// command and applies to all its subcommands.
+	// i.e. foobar Deploy --Force
+	CaseInsensitive bool
+	// Boolean to accept unambiguous prefixes of long flag names given with
+	// two dashes. It is inherited by subcommands.
+	// i.e. foobar --verb -> foobar --verbose
+	PrefixMatchFlags bool
 	// Custom suggest command for matching
 	SuggestCommandFunc SuggestCommandFunc
 	// Flag exclusion group
 	MutuallyExclusiveFlags []MutuallyExclusiveFlags
 	// Arguments to parse for this command
 	Arguments []Argument
-	// Whether to read arguments from stdin
-	// applicable to root command only
+	// Whether to read arguments from stdin, split like a POSIX shell does,
+	// and append them to the arguments given
 	ReadArgsFromStdin bool
+	// Whether to replace arguments of the form @file by the arguments read
+	// from the file, with the same syntax as ReadArgsFromStdin
+	// applicable to root command only
+	ExpandResponseFiles bool
+	// The prefix of arguments passed on with a single literal @ in place of
+	// the prefix when ExpandResponseFiles is set, "@@" by default
+	// i.e. foobar @@user -> foobar @user
+	ResponseFileEscape string
+	// Prefix of the environment variables derived for flags without explicit
+	// Sources, e.g. MYAPP binds the flag "host" of the subcommand "db" to
+	// $MYAPP_DB_HOST. Flags can opt out with DisableEnvPrefix.
+	// applicable to root command only
+	EnvPrefix string
+	// What to do with environment variables which start with EnvPrefix
+	// but are not claimed by any flag, the default is to ignore them
+	// applicable to root command only
+	UnknownEnvVars UnknownEnvVarsAction
+	// Env replaces the process environment for value sources, expanding
+	// flag values and help hints if not nil. It is inherited by subcommands
+	// which do not set their own.
+	Env map[string]string
+	// FS replaces the operating system's file system for value sources and
+	// config files if not nil. Paths are looked up relative to its root, so
+	// /etc/app.ini is read as etc/app.ini. It is inherited by subcommands
+	// which do not set their own.
+	FS fs.FS
 
 	// categories contains the categorized commands and is populated on app startup
 	categories CommandCategories
@@ -137,6 +214,12 @@ type Command struct {
 	flagCategories FlagCategories
 	// flags that have been applied in current parse
 	appliedFlags []Flag
+	// config holds the values loaded from the config files of the root command
+	config *configStore
+	// execOutputs caches the output of commands run by value sources
+	execOutputs map[string]execOutput
+	// sourceCaches holds the file caches used when Env or FS is set
+	sourceCaches map[*fileCache]*fileCache
 	// The parent of this command. This value will be nil for the
 	// command at the root of the graph.
 	parent *Command
@@ -244,6 +327,7 @@ func (cmd *Command) setupDefaults(osArgs []string) {
 	}
 
 	cmd.ensureHelp()
+	cmd.ensureBuiltinFlags()
 
 	if !cmd.HideVersion && isRoot {
 		tracef("appending version flag (cmd=%[1]q)", cmd.Name)
@@ -255,6 +339,11 @@ func (cmd *Command) setupDefaults(osArgs []string) {
 		cmd.SuggestCommandFunc = suggestCommand
 	}
 
+	if cmd.EnableConfigCommand && isRoot {
+		tracef("appending configCommand (cmd=%[1]q)", cmd.Name)
+		cmd.appendCommand(buildConfigCommand())
+	}
+
 	if cmd.EnableShellCompletion || cmd.Root().shellCompletion {
 		completionCommand := buildCompletionCommand()
 
@@ -308,10 +397,26 @@ func (cmd *Command) setupCommandGraph() {
 	}
 }
 
+// resetAppliedFlags lets the persistent flags of the command and its
+// subcommands look up their sources again, as they are only applied once
+// per run
+func (cmd *Command) resetAppliedFlags() {
+	for _, fl := range cmd.allFlags() {
+		if af, ok := fl.(appliedFlag); ok {
+			af.resetApplied()
+		}
+	}
+
+	for _, subCmd := range cmd.Commands {
+		subCmd.resetAppliedFlags()
+	}
+}
+
 func (cmd *Command) setupSubcommand() {
 	tracef("setting up self as sub-command (cmd=%[1]q)", cmd.Name)
 
 	cmd.ensureHelp()
+	cmd.ensureBuiltinFlags()
 
 	tracef("setting command categories (cmd=%[1]q)", cmd.Name)
 	cmd.categories = newCommandCategories()
@@ -345,79 +450,48 @@ func (cmd *Command) ensureHelp() {
 	}
 }
 
+func (cmd *Command) ensureBuiltinFlags() {
+	if DebugFlagsFlag != nil && cmd.Root().EnableDebugFlags {
+		tracef("appending DebugFlagsFlag (cmd=%[1]q)", cmd.Name)
+		cmd.appendFlag(DebugFlagsFlag)
+	}
+
+	if PrintConfigFlag != nil && cmd.Root().EnablePrintConfig {
+		tracef("appending PrintConfigFlag (cmd=%[1]q)", cmd.Name)
+		cmd.appendFlag(PrintConfigFlag)
+	}
+
+	if ConfigFlag != nil && cmd.Root().EnableConfigFile {
+		tracef("appending ConfigFlag (cmd=%[1]q)", cmd.Name)
+		cmd.appendFlag(ConfigFlag)
+	}
+
+	if ProfileFlag != nil && cmd.Root().EnableConfigFile && cmd.Root().EnableProfiles {
+		tracef("appending ProfileFlag (cmd=%[1]q)", cmd.Name)
+		cmd.appendFlag(ProfileFlag)
+	}
+}
+
 func (cmd *Command) parseArgsFromStdin() ([]string, error) {
-	type state int
-	const (
-		STATE_SEARCH_FOR_TOKEN state = -1
-		STATE_IN_STRING        state = 0
-	)
-
-	st := STATE_SEARCH_FOR_TOKEN
-	linenum := 1
-	token := ""
 	args := []string{}
+	tokenizer := newArgTokenizer(cmd.Root().Reader)
 
-	breader := bufio.NewReader(cmd.Reader)
-
-outer:
 	for {
-		ch, _, err := breader.ReadRune()
+		arg, err := tokenizer.next()
 		if err == io.EOF {
-			switch st {
-			case STATE_SEARCH_FOR_TOKEN:
-				if token != "--" {
-					args = append(args, token)
-				}
-			case STATE_IN_STRING:
-				// make sure string is not empty
-				for _, t := range token {
-					if !unicode.IsSpace(t) {
-						args = append(args, token)
-					}
-				}
-			}
-			break outer
+			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("could not parse arguments from stdin: %[1]w", err)
 		}
-		switch st {
-		case STATE_SEARCH_FOR_TOKEN:
-			if unicode.IsSpace(ch) || ch == '"' {
-				if ch == '\n' {
-					linenum++
-				}
-				if token != "" {
-					// end the processing here
-					if token == "--" {
-						break outer
-					}
-					args = append(args, token)
-					token = ""
-				}
-				if ch == '"' {
-					st = STATE_IN_STRING
-				}
-				continue
-			}
-			token += string(ch)
-		case STATE_IN_STRING:
-			if ch != '"' {
-				token += string(ch)
-			} else {
-				if token != "" {
-					args = append(args, token)
-					token = ""
-				}
-				/*else {
-					//TODO. Should we pass in empty strings ?
-				}*/
-				st = STATE_SEARCH_FOR_TOKEN
-			}
+		// end the processing here
+		if arg == "--" {
+			break
 		}
+		args = append(args, arg)
 	}
 
-	tracef("parsed stdin args as %v (cmd=%[2]q)", args, cmd.Name)
+	tracef("parsed stdin args as %[1]v (cmd=%[2]q)", args, cmd.Name)
 
 	return args, nil
 }
@@ -434,13 +508,21 @@ func (cmd *Command) Run(ctx context.Context, osArgs []string) (deferErr error) {
 		cmd.parent = v
 	}
 
+	if cmd.ReadArgsFromStdin {
+		if args, err := cmd.parseArgsFromStdin(); err != nil {
+			return err
+		} else {
+			osArgs = append(osArgs, args...)
+		}
+	}
+
 	if cmd.parent == nil {
-		if cmd.ReadArgsFromStdin {
-			if args, err := cmd.parseArgsFromStdin(); err != nil {
+		if cmd.ExpandResponseFiles && len(osArgs) > 0 {
+			args, err := cmd.expandResponseFiles(osArgs[1:], 0)
+			if err != nil {
 				return err
-			} else {
-				osArgs = append(osArgs, args...)
 			}
+			osArgs = append([]string{osArgs[0]}, args...)
 		}
 		// handle the completion flag separately from the flagset since
 		// completion could be attempted after a flag, but before its value was put
@@ -461,10 +543,30 @@ func (cmd *Command) Run(ctx context.Context, osArgs []string) (deferErr error) {
 	ctx = context.WithValue(ctx, commandContextKey, cmd)
 
 	if cmd.parent == nil {
+		cmd.execOutputs = nil
+		cmd.sourceCaches = nil
 		cmd.setupCommandGraph()
+		cmd.setupEnvPrefix()
+		cmd.resetAppliedFlags()
+
+		if cmd.CaseInsensitive {
+			if err := cmd.checkCaseCollisions(); err != nil {
+				return err
+			}
+		}
+
+		if err := cmd.setupConfig(ctx, osArgs); err != nil && !cmd.shellCompletion {
+			return err
+		}
+
+		if !cmd.shellCompletion {
+			if err := cmd.checkUnknownEnvVars(); err != nil {
+				return err
+			}
+		}
 	}
 
-	args, err := cmd.parseFlags(&stringSliceArgs{v: osArgs})
+	args, err := cmd.parseFlags(ctx, &stringSliceArgs{v: osArgs})
 
 	tracef("using post-parse arguments %[1]q (cmd=%[2]q)", args, cmd.Name)
 
@@ -530,10 +632,41 @@ func (cmd *Command) Run(ctx context.Context, osArgs []string) (deferErr error) {
 		}()
 	}
 
-	if err := cmd.checkRequiredFlags(); err != nil {
-		cmd.isInError = true
-		_ = ShowSubcommandHelp(cmd)
-		return err
+	subCmd := cmd.findSubcommand(args)
+
+	if !cmd.Root().shellCompletion {
+		// persistent flags can still be set on the command line of the
+		// subcommand, which resolves them once it is parsed
+		if err := cmd.resolveDeferredSources(ctx, subCmd != nil); err != nil {
+			return err
+		}
+	}
+
+	// like help, the flag dumps are wanted most when the command line is
+	// incomplete, so they are not held up by missing required flags
+	debugFlags := cmd.Root().EnableDebugFlags && checkDebugFlags(cmd)
+	printFormat := ""
+	if cmd.Root().EnablePrintConfig {
+		printFormat = lookupPrintConfigFormat(cmd)
+	}
+
+	if subCmd == nil && debugFlags {
+		tracef("printing flag debug output (cmd=%[1]q)", cmd.Name)
+		FlagDebugPrinter(cmd)
+		return nil
+	}
+
+	if subCmd == nil && printFormat != "" {
+		tracef("printing effective configuration as %[1]q (cmd=%[2]q)", printFormat, cmd.Name)
+		return ConfigPrinter(cmd, printFormat)
+	}
+
+	if !debugFlags && printFormat == "" {
+		if err := cmd.checkRequiredFlags(); err != nil {
+			cmd.isInError = true
+			_ = ShowSubcommandHelp(cmd)
+			return err
+		}
 	}
 
 	for _, grp := range cmd.MutuallyExclusiveFlags {
@@ -556,6 +689,48 @@ func (cmd *Command) Run(ctx context.Context, osArgs []string) (deferErr error) {
 		return err
 	}
 
+	if subCmd != nil {
+		tracef("running sub-command %[1]q with arguments %[2]q (cmd=%[3]q)", subCmd.Name, cmd.Args(), cmd.Name)
+		return subCmd.Run(ctx, cmd.Args().Slice())
+	}
+
+	if cmd.Action == nil {
+		cmd.Action = helpCommandAction
+	} else {
+		if err := cmd.checkPersistentRequiredFlags(); err != nil {
+			cmd.isInError = true
+			_ = ShowSubcommandHelp(cmd)
+			return err
+		}
+
+		if len(cmd.Arguments) > 0 {
+			rargs, err := cmd.parseArguments(ctx, cmd.Args().Slice())
+			if err != nil {
+				tracef("calling with %[1]v (cmd=%[2]q)", err, cmd.Name)
+				return err
+			}
+			cmd.parsedArgs = &stringSliceArgs{v: rargs}
+
+			tracef("running argument actions (cmd=%[1]q)", cmd.Name)
+
+			if err := cmd.runArgumentActions(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := cmd.Action(ctx, cmd); err != nil {
+		tracef("calling handleExitCoder with %[1]v (cmd=%[2]q)", err, cmd.Name)
+		deferErr = cmd.handleExitCoder(ctx, err)
+	}
+
+	tracef("returning deferErr (cmd=%[1]q)", cmd.Name)
+	return deferErr
+}
+
+// findSubcommand returns the subcommand to run with the given positional
+// arguments, or nil when the command itself runs
+func (cmd *Command) findSubcommand(args Args) *Command {
 	var subCmd *Command
 
 	if args.Present() {
@@ -604,42 +779,7 @@ func (cmd *Command) Run(ctx context.Context, osArgs []string) (deferErr error) {
 		}
 	}
 
-	if subCmd != nil {
-		tracef("running sub-command %[1]q with arguments %[2]q (cmd=%[3]q)", subCmd.Name, cmd.Args(), cmd.Name)
-		return subCmd.Run(ctx, cmd.Args().Slice())
-	}
-
-	if cmd.Action == nil {
-		cmd.Action = helpCommandAction
-	} else {
-		if err := cmd.checkPersistentRequiredFlags(); err != nil {
-			cmd.isInError = true
-			_ = ShowSubcommandHelp(cmd)
-			return err
-		}
-
-		if len(cmd.Arguments) > 0 {
-			rargs := cmd.Args().Slice()
-			tracef("calling argparse with %[1]v", rargs)
-			for _, arg := range cmd.Arguments {
-				var err error
-				rargs, err = arg.Parse(rargs)
-				if err != nil {
-					tracef("calling with %[1]v (cmd=%[2]q)", err, cmd.Name)
-					return err
-				}
-			}
-			cmd.parsedArgs = &stringSliceArgs{v: rargs}
-		}
-	}
-
-	if err := cmd.Action(ctx, cmd); err != nil {
-		tracef("calling handleExitCoder with %[1]v (cmd=%[2]q)", err, cmd.Name)
-		deferErr = cmd.handleExitCoder(ctx, err)
-	}
-
-	tracef("returning deferErr (cmd=%[1]q)", cmd.Name)
-	return deferErr
+	return subCmd
 }
 
 func (cmd *Command) checkHelp() bool {
@@ -691,6 +831,112 @@ func (cmd *Command) useShortOptionHandling() bool {
 	return false
 }
 
+// permuteFlags traverses Lineage() for *any* ancestors with PermuteFlags
+func (cmd *Command) permuteFlags() bool {
+	for _, pCmd := range cmd.Lineage() {
+		if pCmd.PermuteFlags {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prefixMatchFlags traverses Lineage() for *any* ancestors with
+// PrefixMatchFlags
+func (cmd *Command) prefixMatchFlags() bool {
+	for _, pCmd := range cmd.Lineage() {
+		if pCmd.PrefixMatchFlags {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveFlagName returns the name of the flag applied to the command which
+// was given as name, when it is not the exact name of a flag. With
+// CaseInsensitive this is the name equal to it regardless of case. With
+// PrefixMatchFlags and a long flag this is the long name of the only flag
+// starting with it, or an *AmbiguousFlagError when there are several. It
+// returns "" when no flag matches.
+func (cmd *Command) resolveFlagName(name string, long bool) (string, error) {
+	return cmd.matchFlagName(cmd.appliedFlags, name, long)
+}
+
+// matchFlagName resolves name among the given flags like resolveFlagName
+func (cmd *Command) matchFlagName(flags []Flag, name string, long bool) (string, error) {
+	foldCase := cmd.Root().CaseInsensitive
+	prefix := long && cmd.prefixMatchFlags()
+	if (!foldCase && !prefix) || name == "" {
+		return "", nil
+	}
+
+	if foldCase {
+		for _, fl := range flags {
+			for _, n := range fl.Names() {
+				if strings.EqualFold(n, name) {
+					tracef("resolved flag %[1]q to %[2]q ignoring case (cmd=%[3]q)", name, n, cmd.Name)
+					return n, nil
+				}
+			}
+		}
+	}
+
+	if !prefix {
+		return "", nil
+	}
+
+	seen := map[string]bool{}
+	var candidates []string
+
+	for _, fl := range flags {
+		names := fl.Names()
+		if len(names) == 0 || seen[names[0]] {
+			continue
+		}
+		seen[names[0]] = true
+
+		for _, n := range names {
+			if len(n) > 1 && hasPrefix(n, name, foldCase) {
+				candidates = append(candidates, n)
+				break
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", nil
+	case 1:
+		tracef("resolved flag prefix %[1]q to %[2]q (cmd=%[3]q)", name, candidates[0], cmd.Name)
+		return candidates[0], nil
+	}
+
+	sort.Strings(candidates)
+	for i, n := range candidates {
+		candidates[i] = "--" + n
+	}
+
+	return "", &AmbiguousFlagError{Flag: "--" + name, Candidates: candidates}
+}
+
+// stopsPermutation returns whether permuting flags must stop at the given
+// positional argument because it selects a subcommand, leaving the rest of
+// the arguments to it
+func (cmd *Command) stopsPermutation(arg string) bool {
+	if cmd.DefaultCommand != "" {
+		return true
+	}
+
+	name := arg
+	if cmd.SuggestCommandFunc != nil {
+		name = cmd.SuggestCommandFunc(cmd.Commands, name)
+	}
+
+	return cmd.Command(name) != nil
+}
+
 func (cmd *Command) suggestFlagFromError(err error, commandName string) (string, error) {
 	fl, parseErr := flagFromError(err)
 	if parseErr != nil {
@@ -717,9 +963,13 @@ func (cmd *Command) suggestFlagFromError(err error, commandName string) (string,
 	return fmt.Sprintf(SuggestDidYouMeanTemplate, suggestion) + "\n\n", nil
 }
 
-func (cmd *Command) parseFlags(args Args) (Args, error) {
+func (cmd *Command) parseFlags(ctx context.Context, args Args) (Args, error) {
 	tracef("parsing flags from arguments %[1]q (cmd=%[2]q)", args, cmd.Name)
 
+	for _, fl := range cmd.allFlags() {
+		cmd.bindApplyContext(ctx, fl)
+	}
+
 	cmd.parsedArgs = nil
 	if v, err := cmd.newFlagSet(); err != nil {
 		return args, err
@@ -774,6 +1024,7 @@ func (cmd *Command) parseFlags(args Args) (Args, error) {
 
 			tracef("applying as persistent flag=%[1]q (cmd=%[2]q)", flNames, cmd.Name)
 
+			cmd.bindApplyContext(ctx, fl)
 			if err := fl.Apply(cmd.flagSet); err != nil {
 				return cmd.Args(), err
 			}
@@ -785,7 +1036,21 @@ func (cmd *Command) parseFlags(args Args) (Args, error) {
 
 	tracef("parsing flags iteratively tail=%[1]q (cmd=%[2]q)", args.Tail(), cmd.Name)
 
-	if err := parseIter(cmd.flagSet, cmd, args.Tail(), cmd.Root().shellCompletion); err != nil {
+	if cmd.Root().FlagParser == FlagParserGNU {
+		if err := parseGNU(cmd.flagSet, cmd, args.Tail(), cmd.permuteFlags(), cmd.Root().shellCompletion, cmd.stopsPermutation); err != nil {
+			return cmd.Args(), err
+		}
+
+		tracef("done parsing flags (cmd=%[1]q)", cmd.Name)
+
+		return cmd.Args(), nil
+	}
+
+	if cmd.permuteFlags() {
+		if err := parsePermuted(cmd.flagSet, cmd, args.Tail(), cmd.Root().shellCompletion, cmd.stopsPermutation); err != nil {
+			return cmd.Args(), err
+		}
+	} else if err := parseIter(cmd.flagSet, cmd, args.Tail(), cmd.Root().shellCompletion); err != nil {
 		return cmd.Args(), err
 	}
 
@@ -805,10 +1070,13 @@ func (cmd *Command) Names() []string {
 	return append([]string{cmd.Name}, cmd.Aliases...)
 }
 
-// HasName returns true if Command.Name matches given name
+// HasName returns true if Command.Name matches given name.
+// With CaseInsensitive set on the root command the case of name is ignored.
 func (cmd *Command) HasName(name string) bool {
+	foldCase := cmd.Root().CaseInsensitive
+
 	for _, n := range cmd.Names() {
-		if n == name {
+		if n == name || (foldCase && strings.EqualFold(n, name)) {
 			return true
 		}
 	}
@@ -857,6 +1125,18 @@ func (cmd *Command) VisibleFlags() []Flag {
 	return visibleFlags(cmd.Flags)
 }
 
+// VisibleArguments returns a slice of the Arguments which describe
+// themselves in help output
+func (cmd *Command) VisibleArguments() []DocGenerationArgument {
+	args := []DocGenerationArgument{}
+	for _, arg := range cmd.Arguments {
+		if da, ok := arg.(DocGenerationArgument); ok {
+			args = append(args, da)
+		}
+	}
+	return args
+}
+
 func (cmd *Command) appendFlag(fl Flag) {
 	if !hasFlag(cmd.Flags, fl) {
 		cmd.Flags = append(cmd.Flags, fl)
@@ -10,10 +10,12 @@ import (
 	"net/mail"
 	"os"
 	"reflect"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/require"
@@ -1190,6 +1192,609 @@ func TestCommand_UseShortOptionAfterSliceFlag(t *testing.T) {
 	expect(t, name, expected)
 }
 
+func TestCommand_PermuteFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		permute  bool
+		args     []string
+		expected []string
+		force    bool
+		timeout  int64
+		verbose  bool
+		target   string
+	}{
+		{
+			name:     "flag after argument",
+			permute:  true,
+			args:     []string{"app", "deploy", "web", "--force"},
+			expected: []string{"web"},
+			force:    true,
+			target:   "web",
+		},
+		{
+			name:     "flags between arguments",
+			permute:  true,
+			args:     []string{"app", "deploy", "--force", "web", "-t", "5", "api"},
+			expected: []string{"web", "api"},
+			force:    true,
+			timeout:  5,
+			target:   "web",
+		},
+		{
+			name:     "terminator",
+			permute:  true,
+			args:     []string{"app", "deploy", "web", "--", "--force"},
+			expected: []string{"web", "--force"},
+			target:   "web",
+		},
+		{
+			name:     "terminator as flag value",
+			permute:  true,
+			args:     []string{"app", "deploy", "--name", "--", "web", "--force"},
+			expected: []string{"web"},
+			force:    true,
+			target:   "web",
+		},
+		{
+			name:     "root flags before subcommand",
+			permute:  true,
+			args:     []string{"app", "--verbose", "deploy", "web", "-f"},
+			expected: []string{"web"},
+			force:    true,
+			verbose:  true,
+			target:   "web",
+		},
+		{
+			name:     "disabled",
+			args:     []string{"app", "deploy", "web", "--force"},
+			expected: []string{"web", "--force"},
+			target:   "web",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var args []string
+
+			cmd := &Command{
+				Name:         "app",
+				PermuteFlags: test.permute,
+				Flags: []Flag{
+					&BoolFlag{Name: "verbose"},
+				},
+				Commands: []*Command{
+					{
+						Name: "deploy",
+						Flags: []Flag{
+							&BoolFlag{Name: "force", Aliases: []string{"f"}},
+							&IntFlag{Name: "timeout", Aliases: []string{"t"}},
+							&StringFlag{Name: "name"},
+						},
+						Arguments: []Argument{
+							&StringArg{Name: "target", Min: 1, Max: 1},
+						},
+						Action: func(_ context.Context, cmd *Command) error {
+							args = append([]string{cmd.StringArg("target")}, cmd.Args().Slice()...)
+							return nil
+						},
+					},
+				},
+			}
+
+			require.NoError(t, cmd.Run(buildTestContext(t), test.args))
+
+			deploy := cmd.Command("deploy")
+			require.Equal(t, test.expected, args)
+			require.Equal(t, test.force, deploy.Bool("force"))
+			require.Equal(t, test.timeout, deploy.Int("timeout"))
+			require.Equal(t, test.verbose, cmd.Bool("verbose"))
+			require.Equal(t, test.target, deploy.StringArg("target"))
+		})
+	}
+
+	t.Run("shell completion", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := &Command{
+			Name:                  "app",
+			Writer:                out,
+			EnableShellCompletion: true,
+			PermuteFlags:          true,
+			Commands: []*Command{
+				{
+					Name: "deploy",
+					Flags: []Flag{
+						&BoolFlag{Name: "force"},
+					},
+					ShellComplete: func(_ context.Context, cmd *Command) {
+						fmt.Fprintf(cmd.Root().Writer, "force=%[1]v args=%[2]q", cmd.Bool("force"), cmd.Args().Slice())
+					},
+				},
+			},
+		}
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "deploy", "web", "--force", "--fo", "--generate-shell-completion"}))
+		require.Equal(t, `force=true args=["web" "--fo"]`, out.String())
+	})
+}
+
+func TestCommand_FlagParserGNU(t *testing.T) {
+	type result struct {
+		verbose int
+		extract bool
+		output  string
+		count   int64
+		tags    []string
+		args    []string
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected result
+		err      error
+	}{
+		{
+			name:     "short cluster",
+			args:     []string{"-vx", "a"},
+			expected: result{verbose: 1, extract: true, args: []string{"a"}},
+		},
+		{
+			name:     "counted short cluster",
+			args:     []string{"-vvv"},
+			expected: result{verbose: 3},
+		},
+		{
+			name:     "attached short value",
+			args:     []string{"-ofile", "-n5"},
+			expected: result{output: "file", count: 5},
+		},
+		{
+			name:     "short value with equal sign",
+			args:     []string{"-o=file"},
+			expected: result{output: "file"},
+		},
+		{
+			name:     "separate short value",
+			args:     []string{"-o", "-file"},
+			expected: result{output: "-file"},
+		},
+		{
+			name:     "cluster ending with value",
+			args:     []string{"-vxofile"},
+			expected: result{verbose: 1, extract: true, output: "file"},
+		},
+		{
+			name:     "long values",
+			args:     []string{"--output=file", "--count", "7", "--extract=false"},
+			expected: result{output: "file", count: 7},
+		},
+		{
+			name:     "aliases",
+			args:     []string{"-n", "1", "--count", "2", "-t", "a", "--tag", "b"},
+			expected: result{count: 2, tags: []string{"a", "b"}},
+		},
+		{
+			name:     "terminator",
+			args:     []string{"-v", "--", "-x", "--output"},
+			expected: result{verbose: 1, args: []string{"-x", "--output"}},
+		},
+		{
+			name:     "stops at first argument",
+			args:     []string{"a", "-v"},
+			expected: result{args: []string{"a", "-v"}},
+		},
+		{
+			name:     "single dash argument",
+			args:     []string{"-"},
+			expected: result{args: []string{"-"}},
+		},
+		{
+			name: "long flag with one dash",
+			args: []string{"-verbose"},
+			err:  &FlagSyntaxError{Arg: "-verbose", Msg: "long flags take two dashes, use --verbose"},
+		},
+		{
+			name: "short flag with two dashes",
+			args: []string{"--v"},
+			err:  &FlagSyntaxError{Arg: "--v", Msg: "single-character flags take one dash, use -v"},
+		},
+		{
+			name: "three dashes",
+			args: []string{"---v"},
+			err:  &FlagSyntaxError{Arg: "---v"},
+		},
+		{
+			name: "unknown long flag",
+			args: []string{"--nope"},
+			err:  &UnknownFlagError{Flag: "--nope"},
+		},
+		{
+			name: "unknown short flag",
+			args: []string{"-vz"},
+			err:  &UnknownFlagError{Flag: "-z"},
+		},
+		{
+			name: "missing value",
+			args: []string{"-v", "--output"},
+			err:  &MissingFlagValueError{Flag: "--output"},
+		},
+		{
+			name: "invalid value",
+			args: []string{"-nfive"},
+			err:  &InvalidFlagValueError{Flag: "-n", Value: "five", Err: errors.New(`strconv.ParseInt: parsing "five": invalid syntax`)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got result
+
+			cmd := &Command{
+				Name:       "app",
+				FlagParser: FlagParserGNU,
+				Writer:     io.Discard,
+				ErrWriter:  io.Discard,
+				HideHelp:   true,
+				Flags: []Flag{
+					&BoolFlag{Name: "verbose", Aliases: []string{"v"}, Config: BoolConfig{Count: &got.verbose}},
+					&BoolFlag{Name: "extract", Aliases: []string{"x"}, Destination: &got.extract},
+					&StringFlag{Name: "output", Aliases: []string{"o"}, Destination: &got.output},
+					&IntFlag{Name: "count", Aliases: []string{"n"}, Destination: &got.count},
+					&StringSliceFlag{Name: "tag", Aliases: []string{"t"}, Destination: &got.tags},
+				},
+				Action: func(_ context.Context, cmd *Command) error {
+					got.args = cmd.Args().Slice()
+					return nil
+				},
+			}
+
+			err := cmd.Run(buildTestContext(t), append([]string{"app"}, test.args...))
+			if test.err != nil {
+				require.Error(t, err)
+				require.Equal(t, test.err.Error(), err.Error())
+				require.IsType(t, test.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			if len(got.args) == 0 {
+				got.args = nil
+			}
+			if len(got.tags) == 0 {
+				got.tags = nil
+			}
+			require.Equal(t, test.expected, got)
+		})
+	}
+
+	t.Run("subcommands", func(t *testing.T) {
+		var verbose, force bool
+
+		cmd := &Command{
+			Name:       "app",
+			FlagParser: FlagParserGNU,
+			Flags: []Flag{
+				&BoolFlag{Name: "verbose", Aliases: []string{"v"}, Destination: &verbose, Persistent: true},
+			},
+			Commands: []*Command{
+				{
+					Name:  "deploy",
+					Flags: []Flag{&BoolFlag{Name: "force", Aliases: []string{"f"}, Destination: &force}},
+					Action: func(context.Context, *Command) error {
+						return nil
+					},
+				},
+			},
+		}
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "deploy", "-fv"}))
+		require.True(t, verbose)
+		require.True(t, force)
+	})
+
+	t.Run("permuted", func(t *testing.T) {
+		var args []string
+
+		cmd := &Command{
+			Name:         "app",
+			FlagParser:   FlagParserGNU,
+			PermuteFlags: true,
+			Flags:        []Flag{&StringFlag{Name: "output", Aliases: []string{"o"}}},
+			Action: func(_ context.Context, cmd *Command) error {
+				args = cmd.Args().Slice()
+				return nil
+			},
+		}
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "a", "-ofile", "b", "--", "-c"}))
+		require.Equal(t, []string{"a", "b", "-c"}, args)
+		require.Equal(t, "file", cmd.String("output"))
+	})
+
+	t.Run("suggestion", func(t *testing.T) {
+		errOut := &bytes.Buffer{}
+		cmd := &Command{
+			Name:       "app",
+			FlagParser: FlagParserGNU,
+			Suggest:    true,
+			HideHelp:   true,
+			ErrWriter:  errOut,
+			Flags:      []Flag{&BoolFlag{Name: "verbose"}},
+		}
+
+		require.Error(t, cmd.Run(buildTestContext(t), []string{"app", "--verbos"}))
+		require.Contains(t, errOut.String(), `Did you mean "--verbose"?`)
+	})
+}
+
+func TestCommand_PrefixMatchFlags(t *testing.T) {
+	type result struct {
+		verbose   bool
+		verbosity int64
+		color     string
+		output    string
+	}
+
+	tests := []struct {
+		name     string
+		disabled bool
+		args     []string
+		expected result
+		err      string
+	}{
+		{
+			name:     "unique prefix",
+			args:     []string{"app", "--verbosi", "3"},
+			expected: result{verbosity: 3},
+		},
+		{
+			name:     "prefix with value",
+			args:     []string{"app", "--verbosi=2", "--col", "auto"},
+			expected: result{verbosity: 2, color: "auto"},
+		},
+		{
+			name:     "prefix of several aliases of one flag",
+			args:     []string{"app", "--colo=never"},
+			expected: result{color: "never"},
+		},
+		{
+			name:     "persistent flag of parent",
+			args:     []string{"app", "sub", "--verb", "--out", "file"},
+			expected: result{verbose: true, output: "file"},
+		},
+		{
+			name: "ambiguous prefix",
+			args: []string{"app", "--verbo"},
+			err:  "ambiguous flag --verbo could be --verbose, --verbosity",
+		},
+		{
+			name: "single dash",
+			args: []string{"app", "-verbosi", "3"},
+			err:  "flag provided but not defined: -",
+		},
+		{
+			name:     "disabled",
+			disabled: true,
+			args:     []string{"app", "--verbosi", "3"},
+			err:      "flag provided but not defined: -",
+		},
+	}
+
+	for _, parser := range []FlagParserMode{FlagParserCompat, FlagParserGNU} {
+		for _, test := range tests {
+			t.Run(parser.String()+" "+test.name, func(t *testing.T) {
+				var got result
+
+				cmd := &Command{
+					Name:             "app",
+					FlagParser:       parser,
+					PrefixMatchFlags: !test.disabled,
+					Writer:           io.Discard,
+					ErrWriter:        io.Discard,
+					Flags: []Flag{
+						&BoolFlag{Name: "verbose", Persistent: true, Destination: &got.verbose},
+						&IntFlag{Name: "verbosity", Destination: &got.verbosity},
+						&StringFlag{Name: "color", Aliases: []string{"colour"}, Destination: &got.color},
+					},
+					Commands: []*Command{
+						{
+							Name:   "sub",
+							Flags:  []Flag{&StringFlag{Name: "output", Destination: &got.output}},
+							Action: func(context.Context, *Command) error { return nil },
+						},
+					},
+					Action: func(context.Context, *Command) error { return nil },
+				}
+
+				err := cmd.Run(buildTestContext(t), test.args)
+				if test.err != "" {
+					require.ErrorContains(t, err, test.err)
+					return
+				}
+
+				require.NoError(t, err)
+				require.Equal(t, test.expected, got)
+			})
+		}
+	}
+
+	t.Run("ambiguous error", func(t *testing.T) {
+		cmd := &Command{
+			Name:             "app",
+			PrefixMatchFlags: true,
+			Writer:           io.Discard,
+			ErrWriter:        io.Discard,
+			Flags: []Flag{
+				&BoolFlag{Name: "verbose"},
+				&IntFlag{Name: "verbosity"},
+			},
+		}
+
+		var ambiguousErr *AmbiguousFlagError
+		require.ErrorAs(t, cmd.Run(buildTestContext(t), []string{"app", "--verb"}), &ambiguousErr)
+		require.Equal(t, []string{"--verbose", "--verbosity"}, ambiguousErr.Candidates)
+	})
+
+	t.Run("shell completion", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := &Command{
+			Name:                  "app",
+			Writer:                out,
+			EnableShellCompletion: true,
+			PrefixMatchFlags:      true,
+			Flags: []Flag{
+				&BoolFlag{Name: "verbose"},
+				&IntFlag{Name: "verbosity"},
+			},
+			ShellComplete: func(_ context.Context, cmd *Command) {
+				fmt.Fprintf(cmd.Root().Writer, "verbose=%[1]v", cmd.Bool("verbose"))
+			},
+		}
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "--verbose", "--verbo", "--generate-shell-completion"}))
+		require.Equal(t, "verbose=true", out.String())
+	})
+}
+
+func TestCommand_CaseInsensitive(t *testing.T) {
+	newCmd := func(parser FlagParserMode, got *[]string) *Command {
+		return &Command{
+			Name:            "app",
+			CaseInsensitive: true,
+			FlagParser:      parser,
+			Writer:          io.Discard,
+			Flags: []Flag{
+				&BoolFlag{Name: "verbose", Aliases: []string{"v"}, Persistent: true},
+			},
+			Commands: []*Command{
+				{
+					Name:    "deploy",
+					Aliases: []string{"dep"},
+					Flags: []Flag{
+						&BoolFlag{Name: "force"},
+						&StringFlag{Name: "region", Aliases: []string{"r"}},
+					},
+					Arguments: []Argument{
+						&StringArg{Name: "level", Max: 1, Choices: []string{"debug", "info"}},
+					},
+					Action: func(_ context.Context, cmd *Command) error {
+						*got = []string{
+							fmt.Sprint(cmd.Bool("verbose")),
+							fmt.Sprint(cmd.Bool("force")),
+							cmd.String("region"),
+							cmd.StringArg("level"),
+						}
+						return nil
+					},
+				},
+			},
+		}
+	}
+
+	for _, parser := range []FlagParserMode{FlagParserCompat, FlagParserGNU} {
+		t.Run(parser.String(), func(t *testing.T) {
+			var got []string
+
+			cmd := newCmd(parser, &got)
+			require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "Deploy", "--Force", "--VERBOSE", "-R", "eu", "INFO"}))
+			require.Equal(t, []string{"true", "true", "eu", "info"}, got)
+
+			cmd = newCmd(parser, &got)
+			require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "DEP", "-V"}))
+			require.Equal(t, []string{"true", "false", "", ""}, got)
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		var got []string
+
+		cmd := newCmd(FlagParserCompat, &got)
+		cmd.CaseInsensitive = false
+		cmd.ErrWriter = io.Discard
+
+		require.False(t, cmd.Commands[0].HasName("Deploy"))
+		require.Error(t, cmd.Run(buildTestContext(t), []string{"app", "deploy", "--Force"}))
+	})
+
+	t.Run("canonical help", func(t *testing.T) {
+		var got []string
+		out := &bytes.Buffer{}
+
+		cmd := newCmd(FlagParserCompat, &got)
+		cmd.Writer = out
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "DEPLOY", "--HELP"}))
+		require.Contains(t, out.String(), "app deploy")
+		require.Contains(t, out.String(), "--force")
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		var got []string
+		out := &bytes.Buffer{}
+
+		origArgv := os.Args
+		t.Cleanup(func() { os.Args = origArgv })
+		os.Args = []string{"app", "--VERB", "--generate-shell-completion"}
+
+		cmd := newCmd(FlagParserCompat, &got)
+		cmd.Writer = out
+		cmd.EnableShellCompletion = true
+
+		require.NoError(t, cmd.Run(buildTestContext(t), os.Args))
+		require.Equal(t, "--verbose\n", out.String())
+	})
+
+	collisions := []struct {
+		name string
+		cmd  *Command
+		err  string
+	}{
+		{
+			name: "commands",
+			cmd: &Command{
+				Name:     "app",
+				Commands: []*Command{{Name: "deploy"}, {Name: "build", Aliases: []string{"Deploy"}}},
+			},
+			err: `commands "deploy" and "Deploy" of command "app" collide when case is ignored`,
+		},
+		{
+			name: "flags",
+			cmd: &Command{
+				Name:  "app",
+				Flags: []Flag{&BoolFlag{Name: "force"}, &BoolFlag{Name: "Force"}},
+			},
+			err: `flags "force" and "Force" of command "app" collide when case is ignored`,
+		},
+		{
+			name: "persistent flags",
+			cmd: &Command{
+				Name:  "app",
+				Flags: []Flag{&BoolFlag{Name: "debug", Aliases: []string{"d"}, Persistent: true}},
+				Commands: []*Command{
+					{Name: "deploy", Flags: []Flag{&BoolFlag{Name: "dry-run", Aliases: []string{"D"}}}},
+				},
+			},
+			err: `flags "D" and "d" of command "app deploy" collide when case is ignored`,
+		},
+		{
+			name: "choices",
+			cmd: &Command{
+				Name:      "app",
+				Arguments: []Argument{&StringArg{Name: "mode", Choices: []string{"fast", "FAST"}}},
+			},
+			err: `choices "fast" and "FAST" of argument mode of command "app" collide when case is ignored`,
+		},
+	}
+
+	for _, test := range collisions {
+		t.Run("collision "+test.name, func(t *testing.T) {
+			test.cmd.CaseInsensitive = true
+			test.cmd.Writer = io.Discard
+			require.EqualError(t, test.cmd.Run(buildTestContext(t), []string{"app"}), test.err)
+		})
+	}
+}
+
 func TestCommand_Float64Flag(t *testing.T) {
 	var meters float64
 
@@ -3997,10 +4602,8 @@ func TestCommandReadArgsFromStdIn(t *testing.T) {
 			input: `
 			"
 			`,
-			args:          []string{"foo"},
-			expectedInt:   0,
-			expectedFloat: 0,
-			expectedSlice: []string{},
+			args:        []string{"foo"},
+			expectError: true,
 		},
 		{
 			name: "invalid string2",
@@ -4017,9 +4620,36 @@ func TestCommandReadArgsFromStdIn(t *testing.T) {
 			--ssf
 			"
 			hello
+			`,
+			args:        []string{"foo"},
+			expectError: true,
+		},
+		{
+			name: "quotes and escapes",
+			input: `
+			--ssf 'it''s' --ssf "say \"hi\" \n" --ssf a\ b\'c
+			--ssf ""
+			`,
+			args:          []string{"foo"},
+			expectedSlice: []string{"its", "say \"hi\" \\n", "a b'c", ""},
+		},
+		{
+			name: "comments and continuations",
+			input: `
+			# the count
+			--if 100 # not --ff 1
+			--ssf hel\
+lo --ssf a#b
 			`,
 			args:          []string{"foo"},
-			expectedSlice: []string{"hello"},
+			expectedInt:   100,
+			expectedSlice: []string{"hello", "a#b"},
+		},
+		{
+			name:        "trailing backslash",
+			input:       `--ssf a\`,
+			args:        []string{"foo"},
+			expectError: true,
 		},
 	}
 
@@ -4069,3 +4699,553 @@ func TestCommandReadArgsFromStdIn(t *testing.T) {
 		})
 	}
 }
+
+func TestCommandReadArgsFromStdIn_SyntaxError(t *testing.T) {
+	cmd := buildMinimalTestCommand()
+	cmd.ReadArgsFromStdin = true
+	cmd.Reader = strings.NewReader("a\n  b 'c\nd")
+
+	err := cmd.Run(buildTestContext(t), []string{"foo"})
+
+	var syntaxErr *ArgsSyntaxError
+	require.ErrorAs(t, err, &syntaxErr)
+	require.Equal(t, &ArgsSyntaxError{Line: 2, Column: 5, Msg: "unterminated single quote"}, syntaxErr)
+	require.EqualError(t, err, "could not parse arguments from stdin: 2:5: unterminated single quote")
+}
+
+func TestCommandReadArgsFromStdIn_Subcommand(t *testing.T) {
+	var got []string
+
+	cmd := &Command{
+		Name:   "app",
+		Reader: strings.NewReader("--name 'hello world' a"),
+		Commands: []*Command{
+			{
+				Name:              "sub",
+				ReadArgsFromStdin: true,
+				Flags:             []Flag{&StringFlag{Name: "name"}},
+				Action: func(_ context.Context, cmd *Command) error {
+					got = append([]string{cmd.String("name")}, cmd.Args().Slice()...)
+					return nil
+				},
+			},
+		},
+	}
+
+	require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "sub"}))
+	require.Equal(t, []string{"hello world", "a"}, got)
+}
+
+func TestCommand_ResponseFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"args.txt":   {Data: []byte("--name \"hello world\"\n  a\n")},
+		"nested.txt": {Data: []byte("@args.txt b")},
+		"end.txt":    {Data: []byte("-- @args.txt")},
+		"loop.txt":   {Data: []byte("@loop.txt")},
+		"bad.txt":    {Data: []byte("a\n'b")},
+	}
+
+	tests := []struct {
+		name     string
+		disabled bool
+		escape   string
+		args     []string
+		expected []string
+		err      string
+	}{
+		{
+			name:     "response file",
+			args:     []string{"@args.txt", "c"},
+			expected: []string{"hello world", "a", "c"},
+		},
+		{
+			name:     "nested response file",
+			args:     []string{"@nested.txt"},
+			expected: []string{"hello world", "a", "b"},
+		},
+		{
+			name:     "escaped",
+			args:     []string{"@@user", "@"},
+			expected: []string{"", "@user", "@"},
+		},
+		{
+			name:     "custom escape",
+			escape:   "\\@",
+			args:     []string{"\\@user", "@"},
+			expected: []string{"", "@user", "@"},
+		},
+		{
+			name:     "terminator",
+			args:     []string{"a", "--", "@args.txt"},
+			expected: []string{"", "a", "--", "@args.txt"},
+		},
+		{
+			name:     "terminator in response file",
+			args:     []string{"@end.txt", "@nested.txt"},
+			expected: []string{"", "@args.txt", "@nested.txt"},
+		},
+		{
+			name:     "disabled",
+			disabled: true,
+			args:     []string{"@args.txt"},
+			expected: []string{"", "@args.txt"},
+		},
+		{
+			name: "missing",
+			args: []string{"@missing.txt"},
+			err:  "could not read response file: open missing.txt: file does not exist",
+		},
+		{
+			name: "malformed",
+			args: []string{"@bad.txt"},
+			err:  `could not parse response file "bad.txt": 2:1: unterminated single quote`,
+		},
+		{
+			name: "too deep",
+			args: []string{"@loop.txt"},
+			err:  `response file "loop.txt" is nested deeper than 16 files`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+
+			cmd := &Command{
+				Name:                "app",
+				FS:                  fsys,
+				ExpandResponseFiles: !test.disabled,
+				ResponseFileEscape:  test.escape,
+				Flags:               []Flag{&StringFlag{Name: "name"}},
+				Action: func(_ context.Context, cmd *Command) error {
+					got = append([]string{cmd.String("name")}, cmd.Args().Slice()...)
+					return nil
+				},
+			}
+
+			err := cmd.Run(buildTestContext(t), append([]string{"app"}, test.args...))
+			if test.err != "" && test.expected == nil {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestCommand_FlagOrigin(t *testing.T) {
+	t.Setenv("APP_TOKEN", "from-env")
+
+	var (
+		cliOrigin, envOrigin, defaultOrigin FlagOrigin
+		inverseOrigin, subOrigin            FlagOrigin
+	)
+
+	cmd := &Command{
+		Name: "app",
+		Flags: []Flag{
+			&StringFlag{Name: "name", Aliases: []string{"n"}},
+			&StringFlag{Name: "token", Sources: EnvVars("APP_MISSING", "APP_TOKEN")},
+			&IntFlag{Name: "count", Value: 3},
+			&BoolWithInverseFlag{BoolFlag: &BoolFlag{Name: "color"}},
+			&StringFlag{Name: "region", Persistent: true},
+		},
+		Commands: []*Command{
+			{
+				Name: "sub",
+				Action: func(_ context.Context, cmd *Command) error {
+					cliOrigin = cmd.FlagOrigin("n")
+					envOrigin = cmd.FlagOrigin("token")
+					defaultOrigin = cmd.FlagOrigin("count")
+					inverseOrigin = cmd.FlagOrigin("no-color")
+					subOrigin = cmd.FlagOrigin("region")
+					return nil
+				},
+			},
+		},
+	}
+
+	r := require.New(t)
+	r.NoError(cmd.Run(buildTestContext(t), []string{"app", "-n", "bob", "--no-color", "sub", "--region", "eu"}))
+
+	r.Equal(FlagOrigin{Kind: FlagOriginCLI}, cliOrigin)
+	r.Equal("cli", cliOrigin.String())
+
+	r.Equal(FlagOriginSource, envOrigin.Kind)
+	r.Equal(&envVarValueSource{Key: "APP_TOKEN"}, envOrigin.Source)
+	r.Equal(`environment variable "APP_TOKEN"`, envOrigin.String())
+
+	r.Equal(FlagOrigin{}, defaultOrigin)
+	r.Equal("default", defaultOrigin.String())
+
+	r.Equal(FlagOriginCLI, inverseOrigin.Kind)
+	r.Equal(FlagOriginCLI, subOrigin.Kind)
+}
+
+func TestCommand_FlagOrigin_Rerun(t *testing.T) {
+	cmd := &Command{
+		Name: "app",
+		Flags: []Flag{
+			&StringFlag{Name: "name"},
+		},
+		Action: func(context.Context, *Command) error { return nil },
+	}
+
+	r := require.New(t)
+	r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--name", "bob"}))
+	r.Equal(FlagOriginCLI, cmd.FlagOrigin("name").Kind)
+
+	r.NoError(cmd.Run(buildTestContext(t), []string{"app"}))
+	r.Equal(FlagOrigin{}, cmd.FlagOrigin("name"))
+
+	t.Setenv("APP_REGION", "eu")
+	cmd = &Command{
+		Name: "app",
+		Flags: []Flag{
+			&StringFlag{Name: "region", Persistent: true, Sources: EnvVars("APP_REGION")},
+		},
+		Commands: []*Command{
+			{
+				Name:   "serve",
+				Action: func(context.Context, *Command) error { return nil },
+			},
+		},
+	}
+
+	r.NoError(cmd.Run(buildTestContext(t), []string{"app", "serve"}))
+	r.Equal(`environment variable "APP_REGION"`, cmd.Command("serve").FlagOrigin("region").String())
+
+	r.NoError(os.Unsetenv("APP_REGION"))
+	r.NoError(cmd.Run(buildTestContext(t), []string{"app", "serve"}))
+	r.Equal(FlagOrigin{}, cmd.Command("serve").FlagOrigin("region"))
+	r.Equal("", cmd.Command("serve").String("region"))
+}
+
+func TestCommand_FlagOrigin_InvalidFlagAccessHandler(t *testing.T) {
+	var flagName string
+	cmd := &Command{
+		InvalidFlagAccessHandler: func(_ context.Context, _ *Command, name string) {
+			flagName = name
+		},
+		Action: func(_ context.Context, cmd *Command) error {
+			require.Equal(t, FlagOrigin{}, cmd.FlagOrigin("missing"))
+			return nil
+		},
+	}
+
+	require.NoError(t, cmd.Run(buildTestContext(t), []string{"app"}))
+	require.Equal(t, "missing", flagName)
+}
+
+func TestCommand_EnableDebugFlags(t *testing.T) {
+	t.Setenv("APP_PORT", "8080")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "on subcommand",
+			args: []string{"app", "--host", "example.com", "serve", "--debug-flags"},
+			expected: "app --host        example.com  cli\n" +
+				"app --verbose     false        default\n" +
+				"app serve --port  8080         environment variable \"APP_PORT\"\n",
+		},
+		{
+			name: "on root before subcommand",
+			args: []string{"app", "--debug-flags", "serve"},
+			expected: "app --host        localhost  default\n" +
+				"app --verbose     false      default\n" +
+				"app serve --port  8080       environment variable \"APP_PORT\"\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			actionCalled := false
+
+			cmd := &Command{
+				Name:             "app",
+				Writer:           buf,
+				EnableDebugFlags: true,
+				Flags: []Flag{
+					&StringFlag{Name: "host", Value: "localhost"},
+					&BoolFlag{Name: "verbose"},
+				},
+				Commands: []*Command{
+					{
+						Name: "serve",
+						Flags: []Flag{
+							&IntFlag{Name: "port", Sources: EnvVars("APP_PORT")},
+						},
+						Action: func(context.Context, *Command) error {
+							actionCalled = true
+							return nil
+						},
+					},
+				},
+			}
+
+			r := require.New(t)
+			r.NoError(cmd.Run(buildTestContext(t), test.args))
+			r.False(actionCalled)
+			r.Equal(test.expected, buf.String())
+		})
+	}
+
+	t.Run("missing required flag", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		cmd := &Command{
+			Name:             "app",
+			Writer:           buf,
+			EnableDebugFlags: true,
+			Flags:            []Flag{&StringFlag{Name: "token", Required: true}},
+		}
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "--debug-flags"}))
+		require.Equal(t, "app --token    default\n", buf.String())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cmd := &Command{
+			Name:      "app",
+			Writer:    io.Discard,
+			ErrWriter: io.Discard,
+		}
+
+		require.Error(t, cmd.Run(buildTestContext(t), []string{"app", "--debug-flags"}))
+	})
+}
+
+func TestCommand_SourceLookupErrors(t *testing.T) {
+	dir := t.TempDir()
+	dotEnvPath := dir + "/.env"
+	require.NoError(t, os.WriteFile(dotEnvPath, []byte("TOKEN='unterminated\n"), 0o600))
+
+	tests := []struct {
+		name    string
+		sources ValueSourceChain
+		ctx     func() context.Context
+		check   func(r *require.Assertions, err error)
+	}{
+		{
+			name:    "unreadable file",
+			sources: Files(dir),
+			check: func(r *require.Assertions, err error) {
+				r.ErrorContains(err, fmt.Sprintf("could not look up flag token from file %[1]q: read %[1]s: is a directory", dir))
+			},
+		},
+		{
+			name:    "malformed dotenv file",
+			sources: DotEnv(dotEnvPath).EnvVars("TOKEN"),
+			check: func(r *require.Assertions, err error) {
+				r.EqualError(err, fmt.Sprintf(
+					"could not look up flag token from key \"TOKEN\" in dotenv file %[1]q: %[1]s:1: unterminated single-quoted value",
+					dotEnvPath,
+				))
+
+				var lookupErr *SourceLookupError
+				r.ErrorAs(err, &lookupErr)
+				r.Equal("token", lookupErr.Flag)
+
+				var parseErr *SourceParseError
+				r.ErrorAs(err, &parseErr)
+				r.Equal(1, parseErr.Line)
+			},
+		},
+		{
+			name:    "cancelled context",
+			sources: Files(dir + "/missing"),
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			check: func(r *require.Assertions, err error) {
+				r.ErrorIs(err, context.Canceled)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := &Command{
+				Name:      "app",
+				Writer:    io.Discard,
+				ErrWriter: io.Discard,
+				Flags:     []Flag{&StringFlag{Name: "token", Sources: test.sources}},
+			}
+
+			ctx := buildTestContext(t)
+			if test.ctx != nil {
+				ctx = test.ctx()
+			}
+
+			test.check(require.New(t), cmd.Run(ctx, []string{"app"}))
+		})
+	}
+}
+
+func TestCommand_ExecOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	marker := dir + "/runs"
+	helper := ExecOutput("sh", "-c", fmt.Sprintf("echo run >> %[1]s; printf 'secret\\n\\n'", marker))
+
+	runs := func(t *testing.T) int {
+		data, err := os.ReadFile(marker)
+		if errors.Is(err, os.ErrNotExist) {
+			return 0
+		}
+		require.NoError(t, err)
+		return strings.Count(string(data), "run")
+	}
+
+	buildCommand := func(out io.Writer) *Command {
+		return &Command{
+			Name:      "app",
+			Writer:    out,
+			ErrWriter: io.Discard,
+			Flags: []Flag{
+				&StringFlag{Name: "token", Sources: ValueSourceChain{Chain: append(EnvVars("APP_TOKEN").Chain, helper.Chain...)}, Sensitive: true},
+				&StringFlag{Name: "other-token", Sources: helper},
+			},
+			Action: func(context.Context, *Command) error { return nil },
+		}
+	}
+
+	t.Run("value", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(marker))
+		r := require.New(t)
+		cmd := buildCommand(io.Discard)
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app"}))
+		r.Equal("secret", cmd.String("token"))
+		r.Equal("secret", cmd.String("other-token"))
+		r.True(cmd.IsSet("token"))
+		r.Equal(FlagOriginSource, cmd.FlagOrigin("token").Kind)
+		r.Equal(1, runs(t), "output is cached for the run")
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app"}))
+		r.Equal(2, runs(t), "cache is reset for every run")
+	})
+
+	t.Run("not needed", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(marker))
+		t.Setenv("APP_TOKEN", "from-env")
+		r := require.New(t)
+		cmd := buildCommand(io.Discard)
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--other-token", "from-cli"}))
+		r.Equal("from-env", cmd.String("token"))
+		r.Equal("from-cli", cmd.String("other-token"))
+		r.Equal(0, runs(t))
+	})
+
+	t.Run("help", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(marker))
+		out := &bytes.Buffer{}
+		cmd := buildCommand(out)
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "--help"}))
+		require.Contains(t, out.String(), fmt.Sprintf(`[$APP_TOKEN, $(sh -c 'echo run >> %[1]s; printf '\''secret\n\n'\''')]`, marker))
+		require.NotContains(t, out.String(), "secret\n")
+		require.Equal(t, 0, runs(t))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		cmd := &Command{
+			Name:      "app",
+			Writer:    io.Discard,
+			ErrWriter: io.Discard,
+			Flags:     []Flag{&StringFlag{Name: "token", Sources: ExecOutput("sh", "-c", "echo locked >&2; exit 3")}},
+		}
+
+		err := cmd.Run(buildTestContext(t), []string{"app"})
+		require.EqualError(t, err, `could not look up flag token from output of command "sh -c 'echo locked >&2; exit 3'": exit status 3: locked`)
+
+		var lookupErr *SourceLookupError
+		require.ErrorAs(t, err, &lookupErr)
+	})
+
+	t.Run("persistent flag set after subcommand", func(t *testing.T) {
+		r := require.New(t)
+		var got string
+
+		buildCommand := func() *Command {
+			return &Command{
+				Name:      "app",
+				Writer:    io.Discard,
+				ErrWriter: io.Discard,
+				Flags: []Flag{
+					&StringFlag{Name: "token", Persistent: true, Sources: ExecOutput("sh", "-c", "exit 3")},
+				},
+				Commands: []*Command{
+					{
+						Name: "sub",
+						Action: func(_ context.Context, cmd *Command) error {
+							got = cmd.String("token")
+							return nil
+						},
+					},
+				},
+			}
+		}
+
+		r.NoError(buildCommand().Run(buildTestContext(t), []string{"app", "sub", "--token", "from-cli"}))
+		r.Equal("from-cli", got)
+
+		err := buildCommand().Run(buildTestContext(t), []string{"app", "sub"})
+		r.EqualError(err, `could not look up flag token from output of command "sh -c 'exit 3'": exit status 3`)
+	})
+
+	t.Run("persistent flag resolved in subcommand", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(marker))
+		r := require.New(t)
+		var got string
+
+		cmd := &Command{
+			Name:      "app",
+			Writer:    io.Discard,
+			ErrWriter: io.Discard,
+			Flags:     []Flag{&StringFlag{Name: "token", Persistent: true, Sources: helper}},
+			Commands: []*Command{
+				{
+					Name: "sub",
+					Action: func(_ context.Context, cmd *Command) error {
+						got = cmd.String("token")
+						return nil
+					},
+				},
+			},
+		}
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "sub"}))
+		r.Equal("secret", got)
+		r.Equal(1, runs(t))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cmd := &Command{
+			Name:      "app",
+			Writer:    io.Discard,
+			ErrWriter: io.Discard,
+			Flags:     []Flag{&StringFlag{Name: "token", Sources: ExecOutput("sleep", "10")}},
+		}
+
+		ctx, cancel := context.WithTimeout(buildTestContext(t), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := cmd.Run(ctx, []string{"app"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+}
@@ -0,0 +1,474 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ConfigFileParser parses the contents of a configuration file into a flat
+// map of dotted keys such as "serve.port"
+type ConfigFileParser func(path string, data []byte) (map[string]string, error)
+
+// ConfigFileParsers maps file extensions to the parser used for
+// configuration files with that extension. Register additional parsers to
+// support more formats.
+var ConfigFileParsers = map[string]ConfigFileParser{
+	".json": parseJSON,
+	".ini":  parseINI,
+}
+
+// configCache caches parsed configuration files across runs
+var configCache = &fileCache{}
+
+// DefaultConfigPaths returns the conventional search paths for the
+// configuration file of the named application, in order of precedence:
+// the user configuration directory ($XDG_CONFIG_HOME on Unix) followed by
+// /etc on systems other than Windows.
+func DefaultConfigPaths(appName, fileName string) []string {
+	paths := []string{}
+
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, appName, fileName))
+	}
+
+	if runtime.GOOS != "windows" {
+		paths = append(paths, filepath.Join("/etc", appName, fileName))
+	}
+
+	return paths
+}
+
+// configStore holds the merged values of the configuration files loaded
+// for a run of the root command
+type configStore struct {
+	files     []string
+	writePath string
+
+	// raw holds the merged values of all files and rawOrigin the file each
+	// value came from
+	raw       map[string]string
+	rawOrigin map[string]string
+
+	// fileRaw holds the values of each file, fileValues and fileProfileOf
+	// those for the active profile
+	fileRaw       map[string]map[string]string
+	fileValues    map[string]map[string]string
+	fileProfileOf map[string]map[string]string
+
+	// values holds the values for the active profile, origin the file and
+	// profileOf the profile each value came from
+	values    map[string]string
+	origin    map[string]string
+	profileOf map[string]string
+
+	profile  string
+	profiles map[string]string
+
+	// keys and flags describe the flags bound to the config files
+	keys  []string
+	flags map[string]configSchemaEntry
+}
+
+// configSchemaEntry is a flag bound to a config key and its command
+type configSchemaEntry struct {
+	cmd  *Command
+	flag Flag
+}
+
+// configValueSource encapsulates a ValueSource from a key in the merged
+// configuration files of the root command, or in one of them if File is set
+type configValueSource struct {
+	store *configStore
+	Key   string
+	File  string
+}
+
+func (c *configValueSource) Lookup() (string, bool) {
+	if c.store == nil {
+		return "", false
+	}
+
+	if c.File != "" {
+		v, ok := c.store.fileValues[c.File][c.Key]
+		return v, ok
+	}
+
+	v, ok := c.store.values[c.Key]
+	return v, ok
+}
+
+// Describe returns the key and the config file it is looked up in, e.g.
+// serve.port in config.json, or in all config files if it is not bound to one
+func (c *configValueSource) Describe() string {
+	if c.File != "" {
+		return c.Key + " in " + c.File
+	}
+
+	return c.Key + " in config"
+}
+
+func (c *configValueSource) String() string {
+	if c.store != nil && c.File != "" {
+		if profile := c.store.fileProfileOf[c.File][c.Key]; profile != "" {
+			return fmt.Sprintf("key %[1]q of profile %[2]q in config file %[3]q", c.Key, profile, c.File)
+		}
+		return fmt.Sprintf("key %[1]q in config file %[2]q", c.Key, c.File)
+	}
+
+	if c.store != nil {
+		if path, ok := c.store.origin[c.Key]; ok {
+			if profile := c.store.profileOf[c.Key]; profile != "" {
+				return fmt.Sprintf("key %[1]q of profile %[2]q in config file %[3]q", c.Key, profile, path)
+			}
+			return fmt.Sprintf("key %[1]q in config file %[2]q", c.Key, path)
+		}
+	}
+
+	return fmt.Sprintf("key %[1]q in config files", c.Key)
+}
+
+func (c *configValueSource) GoString() string {
+	if c.File != "" {
+		return fmt.Sprintf("&configValueSource{Key:%[1]q, File:%[2]q}", c.Key, c.File)
+	}
+	return fmt.Sprintf("&configValueSource{Key:%[1]q}", c.Key)
+}
+
+// perFile returns a source for the key in each loaded configuration file,
+// in order of precedence
+func (c *configValueSource) perFile() []ValueSource {
+	if c.store == nil {
+		return []ValueSource{c}
+	}
+
+	sources := make([]ValueSource, 0, len(c.store.files))
+	for _, path := range c.store.files {
+		sources = append(sources, &configValueSource{store: c.store, Key: c.Key, File: path})
+	}
+
+	return sources
+}
+
+// configFlag is an interface to enable binding flags to a key in the
+// configuration files of the root command
+type configFlag interface {
+	bindConfig(store *configStore, key string) bool
+	parseConfigValue(val string) (any, error)
+	resolveConfigValue(ctx context.Context, cmd *Command) (any, FlagOrigin, error)
+}
+
+// bindConfig binds the flag to the given configuration key unless the flag
+// opted out
+func (f *FlagBase[T, C, V]) bindConfig(store *configStore, key string) bool {
+	if f.DisableConfig {
+		return false
+	}
+
+	tracef("binding flag %[1]q to config key %[2]q", f.Name, key)
+
+	f.configSource = &configValueSource{store: store, Key: key}
+	return true
+}
+
+// parseConfigValue parses and validates a value for the flag the same way
+// as a value given on the command line
+func (f *FlagBase[T, C, V]) parseConfigValue(val string) (any, error) {
+	value := f.creator.Create(f.Value, new(T), f.Config)
+	if err := value.Set(val); err != nil {
+		return nil, err
+	}
+
+	v, ok := value.Get().(T)
+	if !ok {
+		return nil, &typeError[T]{other: value.Get()}
+	}
+
+	if f.Validator != nil {
+		if err := f.Validator(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+// resolveConfigValue looks up the value and origin the flag has in the
+// given command when it is not set on the command line. The sources are
+// looked up with a copy of the flag, leaving the flag itself untouched.
+func (f *FlagBase[T, C, V]) resolveConfigValue(ctx context.Context, cmd *Command) (any, FlagOrigin, error) {
+	fc := *f
+	fc.Destination = nil
+	fc.count = 0
+	fc.hasBeenSet = false
+	fc.applied = false
+	fc.value = nil
+	fc.origin = FlagOrigin{}
+	fc.deferredSources = nil
+	cmd.bindApplyContext(ctx, &fc)
+
+	set := flag.NewFlagSet(f.Name, flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	if err := fc.Apply(set); err != nil {
+		return nil, FlagOrigin{}, err
+	}
+	if err := fc.resolveDeferredSources(ctx, set); err != nil {
+		return nil, FlagOrigin{}, err
+	}
+
+	return fc.value.Get(), fc.origin, nil
+}
+
+// setupConfig loads the configuration files of the root command and binds
+// the flags of the whole command tree to their configuration keys
+func (cmd *Command) setupConfig(ctx context.Context, osArgs []string) error {
+	if !cmd.EnableConfigFile {
+		return nil
+	}
+
+	store := &configStore{
+		raw:       map[string]string{},
+		rawOrigin: map[string]string{},
+		fileRaw:   map[string]map[string]string{},
+		flags:     map[string]configSchemaEntry{},
+	}
+	cmd.config = store
+	cmd.bindConfigFlags(store, nil)
+
+	path, ok, err := cmd.lookupBuiltinFlagValue(ctx, ConfigFlag, osArgs)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		store.writePath = path
+		if err := store.load(cmd, path, false); err != nil {
+			return err
+		}
+	} else {
+		if len(cmd.ConfigPaths) > 0 {
+			store.writePath = cmd.ConfigPaths[0]
+		}
+
+		for _, path := range cmd.ConfigPaths {
+			if err := store.load(cmd, path, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !cmd.EnableProfiles {
+		store.resolve()
+		return nil
+	}
+
+	profile, err := cmd.lookupProfile(ctx, osArgs)
+	if err != nil {
+		return err
+	}
+
+	return store.selectProfile(profile)
+}
+
+func (cmd *Command) bindConfigFlags(store *configStore, path []string) {
+	for _, fl := range cmd.allFlags() {
+		cf, ok := fl.(configFlag)
+		if !ok || isBuiltinFlag(fl) || len(fl.Names()) == 0 {
+			continue
+		}
+
+		key := configKey(append(append([]string{}, path...), fl.Names()[0])...)
+		if cf.bindConfig(store, key) {
+			if _, ok := store.flags[key]; !ok {
+				store.keys = append(store.keys, key)
+			}
+			store.flags[key] = configSchemaEntry{cmd: cmd, flag: fl}
+		}
+	}
+
+	for _, subCmd := range cmd.Commands {
+		subCmd.bindConfigFlags(store, append(append([]string{}, path...), subCmd.Name))
+	}
+}
+
+// configKey joins the subcommand path and flag name into a dotted key,
+// e.g. ("serve", "port") becomes serve.port
+func configKey(parts ...string) string {
+	return strings.Join(parts, ".")
+}
+
+// load merges the values of the given file below the values of the files
+// loaded before it, reading it from the FS of the given command
+func (cs *configStore) load(cmd *Command, path string, optional bool) error {
+	parse, ok := ConfigFileParsers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return fmt.Errorf("unsupported config file format %[1]q for %[2]q", filepath.Ext(path), path)
+	}
+
+	values, err := cmd.sourceCache(configCache).load(cmd.fileSystem(), path, parse)
+	if err != nil {
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	tracef("loaded config file %[1]q", path)
+	cs.files = append(cs.files, path)
+	cs.fileRaw[path] = values
+
+	for k, v := range values {
+		if _, ok := cs.raw[k]; !ok {
+			cs.raw[k] = v
+			cs.rawOrigin[k] = path
+		}
+	}
+
+	return nil
+}
+
+// resolve computes the values for the active profile, of the merged files
+// as well as of each file
+func (cs *configStore) resolve(chain ...string) {
+	var rawKeys map[string]string
+	cs.values, rawKeys, cs.profileOf = cs.resolveProfile(cs.raw, chain)
+
+	cs.origin = map[string]string{}
+	for key, k := range rawKeys {
+		cs.origin[key] = cs.rawOrigin[k]
+	}
+
+	cs.fileValues = map[string]map[string]string{}
+	cs.fileProfileOf = map[string]map[string]string{}
+	for _, path := range cs.files {
+		cs.fileValues[path], _, cs.fileProfileOf[path] = cs.resolveProfile(cs.fileRaw[path], chain)
+	}
+}
+
+// resolveProfile computes the values of the given raw values for the given
+// profile chain, along with the raw key and the profile each value came
+// from. Values of a profile override those of the profile it extends, which
+// in turn override the values outside of any profile.
+func (cs *configStore) resolveProfile(raw map[string]string, chain []string) (values, rawKeys, profileOf map[string]string) {
+	values = map[string]string{}
+	rawKeys = map[string]string{}
+	profileOf = map[string]string{}
+
+	for k, v := range raw {
+		if cs.profiles != nil && strings.HasPrefix(k, configProfilesKey+".") {
+			continue
+		}
+		values[k] = v
+		rawKeys[k] = k
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		prefix := configKey(configProfilesKey, chain[i]) + "."
+
+		for k, v := range raw {
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+
+			key := strings.TrimPrefix(k, prefix)
+			if key == configExtendsKey {
+				continue
+			}
+
+			values[key] = v
+			rawKeys[key] = k
+			profileOf[key] = chain[i]
+		}
+	}
+
+	return values, rawKeys, profileOf
+}
+
+// lookupBuiltinFlagValue scans the arguments for the value of the given
+// built-in flag, falling back to the sources of the flag. The arguments are
+// scanned before any flag parsing since the configuration must be loaded
+// first. Flag names are resolved like the parser of the command does, so
+// an abbreviated or differently cased name finds the flag too.
+func (cmd *Command) lookupBuiltinFlagValue(ctx context.Context, fl Flag, osArgs []string) (string, bool, error) {
+	if fl == nil {
+		return "", false, nil
+	}
+
+	names := fl.Names()
+	flags := cmd.allFlags()
+
+	for i := 1; i < len(osArgs); i++ {
+		arg := osArgs[i]
+		if arg == "--" {
+			break
+		}
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+
+		long := strings.HasPrefix(arg, "--")
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if !hasFlagName(flags, name) {
+			full, err := cmd.matchFlagName(flags, name, long)
+			if err != nil {
+				// only an ambiguity involving the flag itself matters here,
+				// the argument may belong to a subcommand
+				var ambiguous *AmbiguousFlagError
+				if errors.As(err, &ambiguous) {
+					for _, n := range names {
+						if checkStringSliceIncludes("--"+n, ambiguous.Candidates) {
+							return "", false, err
+						}
+					}
+				}
+			}
+			if full != "" {
+				name = full
+			}
+		}
+
+		for _, n := range names {
+			if n != name {
+				continue
+			}
+
+			if hasValue {
+				return value, true, nil
+			}
+
+			if i+1 < len(osArgs) {
+				return osArgs[i+1], true, nil
+			}
+		}
+	}
+
+	if sf, ok := fl.(*StringFlag); ok {
+		v, source, found, err := sf.Sources.LookupWithSourceContext(ctx)
+		if err != nil {
+			return "", false, &SourceLookupError{Flag: sf.Name, Source: source, Err: err}
+		}
+		if found && v != "" {
+			return v, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// hasFlagName returns whether one of the flags has exactly the given name
+func hasFlagName(flags []Flag, name string) bool {
+	for _, fl := range flags {
+		if checkStringSliceIncludes(name, fl.Names()) {
+			return true
+		}
+	}
+	return false
+}
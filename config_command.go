@@ -0,0 +1,498 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	configCommandName = "config"
+)
+
+func buildConfigCommand() *Command {
+	return &Command{
+		Name:  configCommandName,
+		Usage: "Read and write the configuration file",
+		Commands: []*Command{
+			{
+				Name:      "get",
+				Usage:     "Print the configured value of a key",
+				ArgsUsage: "KEY",
+				Action:    configGetAction,
+			},
+			{
+				Name:      "set",
+				Usage:     "Set a key in the configuration file",
+				ArgsUsage: "KEY VALUE",
+				Action:    configSetAction,
+			},
+			{
+				Name:      "unset",
+				Usage:     "Remove a key from the configuration file",
+				ArgsUsage: "KEY",
+				Action:    configUnsetAction,
+			},
+			{
+				Name:   "profiles",
+				Usage:  "List the profiles defined in the configuration files",
+				Action: configProfilesAction,
+			},
+			{
+				Name:   "list",
+				Usage:  "List the effective value of every key and where it came from",
+				Action: configListAction,
+			},
+		},
+	}
+}
+
+// configEntryFor returns the flag bound to the given key of the root
+// command's config files
+func configEntryFor(cmd *Command, key string) (*configStore, configSchemaEntry, error) {
+	store := cmd.Root().config
+	if store == nil {
+		return nil, configSchemaEntry{}, errors.New("config files are not enabled")
+	}
+
+	entry, ok := store.flags[key]
+	if !ok {
+		msg := fmt.Sprintf("unknown config key %[1]q", key)
+		if suggestion := suggestName(store.keys, key); suggestion != "" {
+			msg += ". " + fmt.Sprintf(SuggestDidYouMeanTemplate, suggestion)
+		}
+		return nil, configSchemaEntry{}, errors.New(msg)
+	}
+
+	return store, entry, nil
+}
+
+func checkConfigArgs(cmd *Command, n int) error {
+	if cmd.Args().Len() != n {
+		return fmt.Errorf("expected %[1]s, got %[2]d arguments", cmd.ArgsUsage, cmd.Args().Len())
+	}
+	return nil
+}
+
+func configGetAction(_ context.Context, cmd *Command) error {
+	if err := checkConfigArgs(cmd, 1); err != nil {
+		return err
+	}
+
+	key := cmd.Args().First()
+	store, _, err := configEntryFor(cmd, key)
+	if err != nil {
+		return err
+	}
+
+	v, ok := store.values[key]
+	if !ok {
+		return fmt.Errorf("config key %[1]q is not set", key)
+	}
+
+	_, err = fmt.Fprintln(cmd.Root().Writer, v)
+	return err
+}
+
+func configSetAction(_ context.Context, cmd *Command) error {
+	if err := checkConfigArgs(cmd, 2); err != nil {
+		return err
+	}
+
+	key, val := cmd.Args().Get(0), cmd.Args().Get(1)
+	store, entry, err := configEntryFor(cmd, key)
+	if err != nil {
+		return err
+	}
+
+	if err := checkConfigWritable(cmd); err != nil {
+		return err
+	}
+
+	v, err := entry.flag.(configFlag).parseConfigValue(val)
+	if err != nil {
+		return fmt.Errorf("invalid value %[1]q for config key %[2]q: %[3]w", val, key, err)
+	}
+
+	return updateConfigFile(store.writePath, store.profileKey(key), entry.flag, v, false)
+}
+
+func configUnsetAction(_ context.Context, cmd *Command) error {
+	if err := checkConfigArgs(cmd, 1); err != nil {
+		return err
+	}
+
+	key := cmd.Args().First()
+	store, entry, err := configEntryFor(cmd, key)
+	if err != nil {
+		return err
+	}
+
+	if err := checkConfigWritable(cmd); err != nil {
+		return err
+	}
+
+	return updateConfigFile(store.writePath, store.profileKey(key), entry.flag, nil, true)
+}
+
+// checkConfigWritable fails when config files are read from an FS, as it
+// cannot be written to
+func checkConfigWritable(cmd *Command) error {
+	if cmd.fileSystem() != nil {
+		return errors.New("config files read from a custom FS cannot be written")
+	}
+	return nil
+}
+
+func configListAction(ctx context.Context, cmd *Command) error {
+	if err := checkConfigArgs(cmd, 0); err != nil {
+		return err
+	}
+
+	root := cmd.Root()
+	store := root.config
+	if store == nil {
+		return errors.New("config files are not enabled")
+	}
+
+	w := tabwriter.NewWriter(root.Writer, 1, 8, 2, ' ', 0)
+
+	for _, key := range store.keys {
+		entry := store.flags[key]
+		name := entry.flag.Names()[0]
+
+		var (
+			value  any
+			origin FlagOrigin
+		)
+
+		if entry.cmd == root {
+			value = root.Value(name)
+			origin = root.FlagOrigin(name)
+		} else {
+			// flags of other commands are not applied in this run, so
+			// resolve their sources in the context of their command
+			var err error
+			value, origin, err = entry.flag.(configFlag).resolveConfigValue(ctx, entry.cmd)
+			if err != nil {
+				return err
+			}
+		}
+
+		s := formatConfigValue(entry.flag, value)
+		if sf, ok := entry.flag.(SensitiveFlag); ok && sf.IsSensitive() {
+			s = maskedValue
+		}
+
+		_, _ = fmt.Fprintf(w, "%[1]s\t%[2]s\t%[3]s\n", key, s, origin)
+	}
+
+	return w.Flush()
+}
+
+// updateConfigFile sets or removes the given key in the config file at path.
+// Values are written in the form read back by the config value source.
+func updateConfigFile(path, key string, fl Flag, value any, unset bool) error {
+	if path == "" {
+		return errors.New("no config file to write to, set ConfigPaths or use --config")
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		data, err = updateJSONConfig(path, key, jsonConfigValue(fl, value), unset)
+	case ".ini":
+		data, err = updateINIConfig(path, key, formatConfigValue(fl, value), unset)
+	default:
+		return fmt.Errorf("writing config file format %[1]q for %[2]q is not supported", ext, path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	tracef("writing config file %[1]q", path)
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+
+	configCache.forget(path)
+	return nil
+}
+
+func readConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func updateJSONConfig(path, key string, value any, unset bool) ([]byte, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root := map[string]any{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&root); err != nil {
+			return nil, jsonParseError(path, data, dec, err)
+		}
+	}
+
+	parts := strings.Split(key, ".")
+	if unset {
+		deleteJSONKey(root, parts)
+	} else {
+		obj := root
+		for i, part := range parts[:len(parts)-1] {
+			nested, ok := obj[part].(map[string]any)
+			if !ok {
+				if _, exists := obj[part]; exists {
+					return nil, fmt.Errorf("config key %[1]q is not an object in %[2]q", strings.Join(parts[:i+1], "."), path)
+				}
+				nested = map[string]any{}
+				obj[part] = nested
+			}
+			obj = nested
+		}
+		obj[parts[len(parts)-1]] = value
+	}
+
+	out, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
+
+// deleteJSONKey removes the key at the given path along with the objects
+// left empty by its removal
+func deleteJSONKey(obj map[string]any, parts []string) {
+	if len(parts) == 1 {
+		delete(obj, parts[0])
+		return
+	}
+
+	if nested, ok := obj[parts[0]].(map[string]any); ok {
+		deleteJSONKey(nested, parts[1:])
+		if len(nested) == 0 {
+			delete(obj, parts[0])
+		}
+	}
+}
+
+// updateINIConfig only edits the lines setting the key, adding it to the end
+// of its section, so that comments and the order of the keys are kept
+func updateINIConfig(path, key, value string, unset bool) ([]byte, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if _, err := parseINI(path, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if !unset {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("value for config key %[1]q cannot contain line breaks in %[2]q", key, path)
+		}
+		quoted, ok := quoteINIValue(value)
+		if !ok {
+			return nil, fmt.Errorf("value for config key %[1]q cannot be quoted in %[2]q", key, path)
+		}
+		value = quoted
+	}
+
+	newline := "\n"
+	if bytes.Contains(data, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+
+	var lines []string
+	if text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"); text != "" {
+		lines = strings.Split(text, "\n")
+	}
+
+	// the lines setting the key, and the last line of each section which
+	// new keys are added after. The file was parsed above, so every line is
+	// well formed.
+	var matches []int
+	sectionEnd := map[string]int{}
+	firstSection := -1
+	section := ""
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == ';' || trimmed[0] == '#' {
+			continue
+		}
+
+		if trimmed[0] == '[' {
+			section = strings.TrimSpace(trimmed[1:strings.IndexByte(trimmed, ']')])
+			sectionEnd[section] = i
+			if firstSection < 0 {
+				firstSection = i
+			}
+			continue
+		}
+
+		sectionEnd[section] = i
+
+		k := strings.TrimSpace(line[:strings.IndexAny(line, "=:")])
+		if section != "" {
+			k = section + "." + k
+		}
+		if k == key {
+			matches = append(matches, i)
+		}
+	}
+
+	switch {
+	case unset:
+		for n := len(matches) - 1; n >= 0; n-- {
+			lines = append(lines[:matches[n]], lines[matches[n]+1:]...)
+		}
+
+	case len(matches) > 0:
+		// the last one wins, so only it is replaced
+		i := matches[len(matches)-1]
+		lines[i] = replaceINIValue(lines[i], value)
+
+	default:
+		section, name := "", key
+		if idx := strings.LastIndexByte(key, '.'); idx >= 0 {
+			section, name = key[:idx], key[idx+1:]
+		}
+		line := name + " = " + value
+
+		if end, ok := sectionEnd[section]; ok {
+			lines = insertLines(lines, end+1, line)
+		} else if section == "" && firstSection >= 0 {
+			lines = insertLines(lines, firstSection, line, "")
+		} else {
+			if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+				lines = append(lines, "")
+			}
+			if section != "" {
+				lines = append(lines, "["+section+"]")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == 0 {
+		return []byte{}, nil
+	}
+
+	return []byte(strings.Join(lines, newline) + newline), nil
+}
+
+// replaceINIValue replaces the value of a key line, keeping the key as
+// written and any comment after the value
+func replaceINIValue(line, value string) string {
+	idx := strings.IndexAny(line, "=:") + 1
+	rest := line[idx:]
+	old := strings.TrimLeft(rest, " \t")
+	prefix := line[:idx] + rest[:len(rest)-len(old)]
+	if prefix == line[:idx] {
+		prefix += " "
+	}
+
+	end := 0
+	if old != "" && (old[0] == '"' || old[0] == '\'') {
+		end = strings.IndexByte(old[1:], old[0]) + 2
+	}
+
+	comment := ""
+	for i := end; i < len(old); i++ {
+		if (old[i] == ';' || old[i] == '#') && (i == 0 || old[i-1] == ' ' || old[i-1] == '\t') {
+			comment = " " + strings.TrimLeft(old[i:], " \t")
+			if j := len(strings.TrimRight(old[:i], " \t")); j > 0 {
+				comment = old[j:]
+			}
+			break
+		}
+	}
+
+	return prefix + value + comment
+}
+
+// insertLines inserts the given lines at index i
+func insertLines(lines []string, i int, add ...string) []string {
+	return append(lines[:i], append(add, lines[i:]...)...)
+}
+
+// quoteINIValue quotes values which would otherwise not be read back as is
+func quoteINIValue(s string) (string, bool) {
+	if s == strings.TrimSpace(s) && !strings.ContainsAny(s, ";#") && !strings.HasPrefix(s, `"`) && !strings.HasPrefix(s, "'") {
+		return s, true
+	}
+
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`, true
+	}
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'", true
+	}
+	return "", false
+}
+
+// writeFileAtomic replaces the file at path with data by writing a
+// temporary file in the same directory and renaming it
+func writeFileAtomic(path string, data []byte) error {
+	perm := fs.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
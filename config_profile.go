@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// configProfilesKey is the config key under which profiles are defined
+	configProfilesKey = "profiles"
+	// configExtendsKey is the key of a profile naming the profile it extends
+	configExtendsKey = "extends"
+)
+
+// ConfigProfiles returns the sorted names of the profiles defined in the
+// config files of the root command
+func (cmd *Command) ConfigProfiles() []string {
+	store := cmd.Root().config
+	if store == nil {
+		return nil
+	}
+
+	return sortedKeys(store.profiles)
+}
+
+// ConfigProfile returns the name of the active profile, or an empty string
+// when no profile is selected
+func (cmd *Command) ConfigProfile() string {
+	store := cmd.Root().config
+	if store == nil {
+		return ""
+	}
+
+	return store.profile
+}
+
+// lookupProfile returns the profile selected with ProfileFlag or with the
+// profile environment variable
+func (cmd *Command) lookupProfile(ctx context.Context, osArgs []string) (string, error) {
+	if v, ok, err := cmd.lookupBuiltinFlagValue(ctx, ProfileFlag, osArgs); ok || err != nil {
+		return v, err
+	}
+
+	if name := cmd.profileEnvVar(); name != "" {
+		v, _ := cmd.lookupEnv(name)
+		return v, nil
+	}
+
+	return "", nil
+}
+
+// profileEnvVar returns the environment variable selecting the profile
+func (cmd *Command) profileEnvVar() string {
+	if !cmd.EnableProfiles {
+		return ""
+	}
+
+	if cmd.ProfileEnvVar != "" {
+		return cmd.ProfileEnvVar
+	}
+
+	if prefix := strings.TrimRight(cmd.EnvPrefix, "_"); prefix != "" {
+		return envVarName(prefix, "profile")
+	}
+
+	return ""
+}
+
+// selectProfile activates the given profile, following the profiles it
+// extends
+func (cs *configStore) selectProfile(profile string) error {
+	cs.profiles = map[string]string{}
+
+	for k, v := range cs.raw {
+		rest, ok := strings.CutPrefix(k, configProfilesKey+".")
+		if !ok {
+			continue
+		}
+
+		name, key, _ := strings.Cut(rest, ".")
+		if _, ok := cs.profiles[name]; !ok {
+			cs.profiles[name] = ""
+		}
+		if key == configExtendsKey {
+			cs.profiles[name] = v
+		}
+	}
+
+	chain := []string{}
+	for name := profile; name != ""; name = cs.profiles[name] {
+		if _, ok := cs.profiles[name]; !ok {
+			if len(chain) > 0 {
+				return fmt.Errorf("config profile %[1]q extends unknown profile %[2]q", chain[len(chain)-1], name)
+			}
+
+			msg := fmt.Sprintf("unknown config profile %[1]q", name)
+			if suggestion := suggestName(sortedKeys(cs.profiles), name); suggestion != "" {
+				msg += ". " + fmt.Sprintf(SuggestDidYouMeanTemplate, suggestion)
+			}
+			return errors.New(msg)
+		}
+
+		for _, seen := range chain {
+			if seen == name {
+				return fmt.Errorf("config profile %[1]q extends itself: %[2]s", profile, strings.Join(append(chain, name), " -> "))
+			}
+		}
+
+		chain = append(chain, name)
+	}
+
+	tracef("selecting config profile chain %[1]q", chain)
+
+	cs.profile = profile
+	cs.resolve(chain...)
+
+	return nil
+}
+
+// profileKey returns the key of the given key in the active profile
+func (cs *configStore) profileKey(key string) string {
+	if cs.profile == "" {
+		return key
+	}
+
+	return configKey(configProfilesKey, cs.profile, key)
+}
+
+func configProfilesAction(_ context.Context, cmd *Command) error {
+	root := cmd.Root()
+	store := root.config
+	if store == nil || store.profiles == nil {
+		return errors.New("config profiles are not enabled")
+	}
+
+	for _, name := range sortedKeys(store.profiles) {
+		marker := " "
+		if name == store.profile {
+			marker = "*"
+		}
+
+		line := marker + " " + name
+		if parent := store.profiles[name]; parent != "" {
+			line += " (extends " + parent + ")"
+		}
+
+		if _, err := fmt.Fprintln(root.Writer, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
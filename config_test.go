@@ -0,0 +1,550 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type configTestValues struct {
+	region  string
+	verbose bool
+	local   string
+	port    int64
+}
+
+func buildConfigTestCommand(vals *configTestValues, paths ...string) *Command {
+	return &Command{
+		Name:             "app",
+		EnableConfigFile: true,
+		ConfigPaths:      paths,
+		Flags: []Flag{
+			&StringFlag{Name: "region", Sources: EnvVars("APP_REGION"), Destination: &vals.region},
+			&BoolFlag{Name: "verbose", Destination: &vals.verbose},
+			&StringFlag{Name: "local", DisableConfig: true, Destination: &vals.local},
+		},
+		Commands: []*Command{
+			{
+				Name: "serve",
+				Flags: []Flag{
+					&IntFlag{Name: "port", Destination: &vals.port},
+				},
+				Action: func(context.Context, *Command) error { return nil },
+			},
+		},
+	}
+}
+
+func writeConfigTestFile(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestCommand_ConfigPaths(t *testing.T) {
+	userPath := writeConfigTestFile(t, "config.json", `{"region": "eu-west-1", "serve": {"port": 8080}}`)
+	systemPath := writeConfigTestFile(t, "config.ini", "region = us-east-1\nverbose = true\nlocal = ignored\n\n[serve]\nport = 80\n")
+	missingPath := filepath.Join(t.TempDir(), "missing.json")
+
+	t.Run("merged in order of precedence", func(t *testing.T) {
+		r := require.New(t)
+		vals := configTestValues{}
+		cmd := buildConfigTestCommand(&vals, missingPath, userPath, systemPath)
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "serve"}))
+		r.Equal(configTestValues{region: "eu-west-1", verbose: true, port: 8080}, vals)
+
+		serve := cmd.Command("serve")
+		r.Equal(fmt.Sprintf("key \"serve.port\" in config file %[1]q", userPath), serve.FlagOrigin("port").String())
+		r.Equal(fmt.Sprintf("key \"verbose\" in config file %[1]q", systemPath), cmd.FlagOrigin("verbose").String())
+	})
+
+	t.Run("environment and command line take precedence", func(t *testing.T) {
+		t.Setenv("APP_REGION", "ap-south-1")
+
+		r := require.New(t)
+		vals := configTestValues{}
+		cmd := buildConfigTestCommand(&vals, userPath, systemPath)
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "serve", "--port", "9090"}))
+		r.Equal(configTestValues{region: "ap-south-1", verbose: true, port: 9090}, vals)
+	})
+
+	t.Run("config flag replaces search paths", func(t *testing.T) {
+		r := require.New(t)
+		vals := configTestValues{}
+		cmd := buildConfigTestCommand(&vals, userPath)
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "serve", "--config=" + systemPath}))
+		r.Equal(configTestValues{region: "us-east-1", verbose: true, port: 80}, vals)
+		r.Equal(systemPath, cmd.Command("serve").String("config"))
+
+		vals = configTestValues{}
+		cmd = buildConfigTestCommand(&vals, userPath)
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--config", systemPath, "serve"}))
+		r.Equal(configTestValues{region: "us-east-1", verbose: true, port: 80}, vals)
+	})
+
+	t.Run("config flag given as prefix", func(t *testing.T) {
+		r := require.New(t)
+		vals := configTestValues{}
+		cmd := buildConfigTestCommand(&vals, userPath)
+		cmd.PrefixMatchFlags = true
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--conf", systemPath, "serve"}))
+		r.Equal(configTestValues{region: "us-east-1", verbose: true, port: 80}, vals)
+
+		cmd = buildConfigTestCommand(&configTestValues{}, userPath)
+		cmd.PrefixMatchFlags = true
+		cmd.Flags = append(cmd.Flags, &StringFlag{Name: "confirm"})
+		cmd.ErrWriter = io.Discard
+
+		r.EqualError(
+			cmd.Run(buildTestContext(t), []string{"app", "--conf", systemPath}),
+			"ambiguous flag --conf could be --config, --confirm",
+		)
+	})
+
+	t.Run("config flag given in other case", func(t *testing.T) {
+		r := require.New(t)
+		vals := configTestValues{}
+		cmd := buildConfigTestCommand(&vals, userPath)
+		cmd.CaseInsensitive = true
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--Config", systemPath, "serve"}))
+		r.Equal(configTestValues{region: "us-east-1", verbose: true, port: 80}, vals)
+	})
+}
+
+func TestCommand_ConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args func(t *testing.T) ([]string, string)
+	}{
+		{
+			name: "missing config file",
+			args: func(t *testing.T) ([]string, string) {
+				path := filepath.Join(t.TempDir(), "missing.json")
+				return []string{"app", "--config", path}, fmt.Sprintf("stat %[1]s: no such file or directory", path)
+			},
+		},
+		{
+			name: "unsupported format",
+			args: func(t *testing.T) ([]string, string) {
+				path := writeConfigTestFile(t, "config.yaml", "region: eu-west-1\n")
+				return []string{"app", "--config", path}, fmt.Sprintf("unsupported config file format \".yaml\" for %[1]q", path)
+			},
+		},
+		{
+			name: "malformed file",
+			args: func(t *testing.T) ([]string, string) {
+				path := writeConfigTestFile(t, "config.ini", "[serve\n")
+				return []string{"app", "--config", path}, fmt.Sprintf("%[1]s:1: unterminated section header \"[serve\"", path)
+			},
+		},
+		{
+			name: "invalid value",
+			args: func(t *testing.T) ([]string, string) {
+				path := writeConfigTestFile(t, "config.json", `{"serve": {"port": "eighty"}}`)
+				return []string{"app", "--config", path, "serve"}, fmt.Sprintf(
+					"could not parse \"eighty\" as int64 value from key \"serve.port\" in config file %[1]q for flag port: "+
+						"strconv.ParseInt: parsing \"eighty\": invalid syntax", path,
+				)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args, msg := test.args(t)
+			cmd := buildConfigTestCommand(&configTestValues{})
+			cmd.ErrWriter = io.Discard
+
+			require.EqualError(t, cmd.Run(buildTestContext(t), args), msg)
+		})
+	}
+}
+
+func TestCommand_ConfigFlagSources(t *testing.T) {
+	oldConfigFlag, oldProfileFlag := ConfigFlag, ProfileFlag
+	defer func() {
+		ConfigFlag, ProfileFlag = oldConfigFlag, oldProfileFlag
+	}()
+
+	ConfigFlag = &StringFlag{Name: "config", Sources: EnvVars("APP_CONFIG")}
+	ProfileFlag = &StringFlag{Name: "profile", Sources: Files("profile")}
+
+	cmd := &Command{
+		Name:             "app",
+		Env:              map[string]string{"APP_CONFIG": "custom.json"},
+		EnableConfigFile: true,
+		EnableProfiles:   true,
+		ConfigPaths:      []string{"config.json"},
+		FS: fstest.MapFS{
+			"custom.json": {Data: []byte(`{"region": "eu", "profiles": {"prod": {"region": "us"}}}`)},
+			"profile":     {Data: []byte("prod")},
+		},
+		Flags:  []Flag{&StringFlag{Name: "region"}},
+		Action: func(context.Context, *Command) error { return nil },
+	}
+
+	r := require.New(t)
+	r.NoError(cmd.Run(buildTestContext(t), []string{"app"}))
+	r.Equal("us", cmd.String("region"))
+	r.Equal("prod", cmd.ConfigProfile())
+}
+
+func TestDefaultConfigPaths(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/home/user/.config")
+
+	paths := DefaultConfigPaths("app", "config.json")
+	if runtime.GOOS == "linux" {
+		require.Equal(t, []string{"/home/user/.config/app/config.json", "/etc/app/config.json"}, paths)
+	} else {
+		require.NotEmpty(t, paths)
+	}
+}
+
+func buildConfigCommandTestCommand(vals *configTestValues, out io.Writer, paths ...string) *Command {
+	cmd := buildConfigTestCommand(vals, paths...)
+	cmd.EnableConfigCommand = true
+	cmd.Writer = out
+	cmd.ErrWriter = io.Discard
+	cmd.Commands[0].Flags = append(cmd.Commands[0].Flags, &StringSliceFlag{Name: "origins"})
+	cmd.Flags = append(cmd.Flags, &StringFlag{
+		Name:      "token",
+		Sensitive: true,
+		Validator: func(s string) error {
+			if s != "" && len(s) < 4 {
+				return fmt.Errorf("token too short")
+			}
+			return nil
+		},
+	})
+	return cmd
+}
+
+func TestCommand_ConfigCommand(t *testing.T) {
+	for _, ext := range []string{".json", ".ini"} {
+		t.Run(ext, func(t *testing.T) {
+			r := require.New(t)
+			path := filepath.Join(t.TempDir(), "app", "config"+ext)
+
+			run := func(args ...string) (string, error) {
+				out := &bytes.Buffer{}
+				cmd := buildConfigCommandTestCommand(&configTestValues{}, out, path)
+				err := cmd.Run(buildTestContext(t), append([]string{"app"}, args...))
+				return out.String(), err
+			}
+
+			for _, args := range [][]string{
+				{"config", "set", "region", "eu-west-1"},
+				{"config", "set", "verbose", "true"},
+				{"config", "set", "serve.port", "8080"},
+				{"config", "set", "serve.origins", "a.example.com,b.example.com"},
+				{"config", "set", "token", "s3cret; really"},
+			} {
+				_, err := run(args...)
+				r.NoError(err)
+			}
+
+			vals := configTestValues{}
+			cmd := buildConfigCommandTestCommand(&vals, io.Discard, path)
+			r.NoError(cmd.Run(buildTestContext(t), []string{"app", "serve"}))
+			r.Equal(configTestValues{region: "eu-west-1", verbose: true, port: 8080}, vals)
+			r.Equal([]string{"a.example.com", "b.example.com"}, cmd.Command("serve").StringSlice("origins"))
+			r.Equal("s3cret; really", cmd.String("token"))
+
+			out, err := run("config", "get", "serve.port")
+			r.NoError(err)
+			r.Equal("8080\n", out)
+
+			_, err = run("config", "unset", "serve.port")
+			r.NoError(err)
+
+			_, err = run("config", "get", "serve.port")
+			r.EqualError(err, `config key "serve.port" is not set`)
+
+			t.Setenv("APP_REGION", "ap-south-1")
+
+			out, err = run("config", "list")
+			r.NoError(err)
+			r.Equal(fmt.Sprintf(`region         ap-south-1                   environment variable "APP_REGION"
+verbose        true                         key "verbose" in config file %[1]q
+token          *****                        key "token" in config file %[1]q
+serve.port     0                            default
+serve.origins  a.example.com,b.example.com  key "serve.origins" in config file %[1]q
+`, path), out)
+		})
+	}
+}
+
+func TestCommand_ConfigCommandINIEdit(t *testing.T) {
+	r := require.New(t)
+	path := writeConfigTestFile(t, "config.ini", `# app settings
+verbose = true
+region = us-east-1  ; closest to the office
+
+[serve]
+; keep in sync with the load balancer
+port: 80
+`)
+
+	for _, args := range [][]string{
+		{"config", "set", "region", "eu-west-1"},
+		{"config", "unset", "verbose"},
+		{"config", "set", "serve.origins", "a.example.com"},
+		{"config", "set", "token", "s3cret; really"},
+	} {
+		cmd := buildConfigCommandTestCommand(&configTestValues{}, io.Discard, path)
+		r.NoError(cmd.Run(buildTestContext(t), append([]string{"app"}, args...)))
+	}
+
+	data, err := os.ReadFile(path)
+	r.NoError(err)
+	r.Equal(`# app settings
+region = eu-west-1  ; closest to the office
+token = "s3cret; really"
+
+[serve]
+; keep in sync with the load balancer
+port: 80
+origins = a.example.com
+`, string(data))
+}
+
+func TestCommand_ConfigCommandListSources(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	r := require.New(t)
+	out := &bytes.Buffer{}
+	dest := "untouched"
+	deployRegion := &StringFlag{Name: "region", Sources: EnvVars("REGION"), Destination: &dest}
+	deployToken := &StringFlag{Name: "token", Sources: ExecOutput("sh", "-c", "echo from-helper")}
+
+	cmd := &Command{
+		Name:                "app",
+		Writer:              out,
+		ErrWriter:           io.Discard,
+		Env:                 map[string]string{"REGION": "eu"},
+		FS:                  fstest.MapFS{"config.json": {Data: []byte(`{"deploy": {"zone": "a"}}`)}},
+		EnableConfigFile:    true,
+		EnableConfigCommand: true,
+		ConfigPaths:         []string{"config.json"},
+		Flags:               []Flag{&StringFlag{Name: "region", Sources: EnvVars("REGION")}},
+		Commands: []*Command{
+			{
+				Name:  "deploy",
+				Flags: []Flag{deployRegion, deployToken, &StringFlag{Name: "zone"}},
+			},
+		},
+	}
+
+	r.NoError(cmd.Run(buildTestContext(t), []string{"app", "config", "list"}))
+	r.Equal(`region         eu           environment variable "REGION"
+deploy.region  eu           environment variable "REGION"
+deploy.token   from-helper  output of command "sh -c 'echo from-helper'"
+deploy.zone    a            key "deploy.zone" in config file "config.json"
+`, out.String())
+
+	r.Equal("untouched", dest)
+	r.False(deployRegion.IsSet())
+	r.Equal(FlagOrigin{}, deployRegion.GetOrigin())
+	r.False(deployToken.IsSet())
+}
+
+func TestCommand_ConfigCommandErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	tests := []struct {
+		args []string
+		msg  string
+	}{
+		{
+			args: []string{"config", "set", "serve.prot", "80"},
+			msg:  `unknown config key "serve.prot". Did you mean "serve.port"?`,
+		},
+		{
+			args: []string{"config", "set", "local", "x"},
+			msg:  `unknown config key "local". Did you mean "token"?`,
+		},
+		{
+			args: []string{"config", "set", "serve.port", "eighty"},
+			msg:  `invalid value "eighty" for config key "serve.port": strconv.ParseInt: parsing "eighty": invalid syntax`,
+		},
+		{
+			args: []string{"config", "set", "token", "abc"},
+			msg:  `invalid value "abc" for config key "token": token too short`,
+		},
+		{
+			args: []string{"config", "set", "region"},
+			msg:  "expected KEY VALUE, got 1 arguments",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			cmd := buildConfigCommandTestCommand(&configTestValues{}, io.Discard, path)
+			err := cmd.Run(buildTestContext(t), append([]string{"app"}, test.args...))
+			require.EqualError(t, err, test.msg)
+		})
+	}
+
+	_, err := os.Stat(path)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestCommand_ConfigProfiles(t *testing.T) {
+	path := writeConfigTestFile(t, "config.json", `{
+  "region": "us-east-1",
+  "profiles": {
+    "base": {"verbose": true, "serve": {"port": 80}},
+    "prod": {"extends": "base", "region": "eu-west-1"},
+    "broken": {"serve": {"port": "eighty"}},
+    "loop": {"extends": "loop2"},
+    "loop2": {"extends": "loop"},
+    "orphan": {"extends": "missing"}
+  }
+}`)
+
+	build := func(vals *configTestValues, out io.Writer) *Command {
+		cmd := buildConfigTestCommand(vals, path)
+		cmd.EnableProfiles = true
+		cmd.EnableConfigCommand = true
+		cmd.EnablePrintConfig = true
+		cmd.Writer = out
+		cmd.ErrWriter = io.Discard
+		return cmd
+	}
+
+	t.Run("selected with flag", func(t *testing.T) {
+		r := require.New(t)
+		vals := configTestValues{}
+		cmd := build(&vals, io.Discard)
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--profile", "prod", "serve"}))
+		r.Equal(configTestValues{region: "eu-west-1", verbose: true, port: 80}, vals)
+		r.Equal("prod", cmd.ConfigProfile())
+		r.Equal([]string{"base", "broken", "loop", "loop2", "orphan", "prod"}, cmd.ConfigProfiles())
+		r.Equal(
+			fmt.Sprintf("key \"serve.port\" of profile \"base\" in config file %[1]q", path),
+			cmd.Command("serve").FlagOrigin("port").String(),
+		)
+		r.Equal(
+			fmt.Sprintf("key \"region\" of profile \"prod\" in config file %[1]q", path),
+			cmd.FlagOrigin("region").String(),
+		)
+	})
+
+	t.Run("selected with flag prefix", func(t *testing.T) {
+		r := require.New(t)
+		vals := configTestValues{}
+		cmd := build(&vals, io.Discard)
+		cmd.PrefixMatchFlags = true
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--prof=prod", "serve"}))
+		r.Equal(configTestValues{region: "eu-west-1", verbose: true, port: 80}, vals)
+		r.Equal("prod", cmd.ConfigProfile())
+	})
+
+	t.Run("selected with flag in other case", func(t *testing.T) {
+		r := require.New(t)
+		vals := configTestValues{}
+		cmd := build(&vals, io.Discard)
+		cmd.CaseInsensitive = true
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--Profile", "prod", "serve"}))
+		r.Equal(configTestValues{region: "eu-west-1", verbose: true, port: 80}, vals)
+		r.Equal("prod", cmd.ConfigProfile())
+	})
+
+	t.Run("selected with environment variable", func(t *testing.T) {
+		t.Setenv("APP_PROFILE", "base")
+
+		r := require.New(t)
+		vals := configTestValues{}
+		cmd := build(&vals, io.Discard)
+		cmd.EnvPrefix = "APP"
+		cmd.UnknownEnvVars = UnknownEnvVarsError
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "serve"}))
+		r.Equal(configTestValues{region: "us-east-1", verbose: true, port: 80}, vals)
+
+		t.Setenv("MY_PROFILE", "prod")
+
+		vals = configTestValues{}
+		cmd = build(&vals, io.Discard)
+		cmd.ProfileEnvVar = "MY_PROFILE"
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "serve"}))
+		r.Equal(configTestValues{region: "eu-west-1", verbose: true, port: 80}, vals)
+	})
+
+	t.Run("listed", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := build(&configTestValues{}, out)
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "--profile", "prod", "config", "profiles"}))
+		require.Equal(t, `  base
+  broken
+  loop (extends loop2)
+  loop2 (extends loop)
+  orphan (extends missing)
+* prod (extends base)
+`, out.String())
+	})
+
+	t.Run("shown in print-config", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := build(&configTestValues{}, out)
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "--profile", "prod", "--print-config", "args"}))
+		require.Equal(t, "app --profile=prod --region=eu-west-1 --verbose --local=\n", out.String())
+	})
+
+	t.Run("written to active profile", func(t *testing.T) {
+		r := require.New(t)
+		setPath := writeConfigTestFile(t, "config.json", `{"region": "us-east-1", "profiles": {"prod": {}}}`)
+		cmd := build(&configTestValues{}, io.Discard)
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--config", setPath, "--profile", "prod", "config", "set", "serve.port", "443"}))
+
+		data, err := os.ReadFile(setPath)
+		r.NoError(err)
+		r.JSONEq(`{"region": "us-east-1", "profiles": {"prod": {"serve": {"port": 443}}}}`, string(data))
+	})
+
+	for _, test := range []struct {
+		profile string
+		msg     string
+	}{
+		{profile: "prd", msg: `unknown config profile "prd". Did you mean "prod"?`},
+		{profile: "orphan", msg: `config profile "orphan" extends unknown profile "missing"`},
+		{profile: "loop", msg: `config profile "loop" extends itself: loop -> loop2 -> loop`},
+		{
+			profile: "broken",
+			msg: fmt.Sprintf(
+				"could not parse \"eighty\" as int64 value from key \"serve.port\" of profile \"broken\" in config file %[1]q "+
+					"for flag port: strconv.ParseInt: parsing \"eighty\": invalid syntax", path,
+			),
+		},
+	} {
+		t.Run("error "+test.profile, func(t *testing.T) {
+			cmd := build(&configTestValues{}, io.Discard)
+			require.EqualError(t, cmd.Run(buildTestContext(t), []string{"app", "--profile", test.profile, "serve"}), test.msg)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// envPrefixFlag is an interface to enable binding flags to an
+// environment variable derived from the root command's EnvPrefix
+type envPrefixFlag interface {
+	bindEnvPrefix(key string)
+}
+
+// bindEnvPrefix binds the flag to the given derived environment variable
+// unless the flag opted out or already declares its own Sources
+func (f *FlagBase[T, C, V]) bindEnvPrefix(key string) {
+	if f.DisableEnvPrefix || f.derivedEnvVar != "" || len(f.Sources.Chain) > 0 {
+		return
+	}
+
+	tracef("binding flag %[1]q to derived environment variable %[2]q", f.Name, key)
+
+	f.derivedEnvVar = key
+	f.Sources = EnvVars(key)
+}
+
+// setupEnvPrefix derives environment variables for the flags of this
+// command and all of its subcommands from the root command's EnvPrefix
+func (cmd *Command) setupEnvPrefix() {
+	prefix := strings.TrimRight(cmd.Root().EnvPrefix, "_")
+	if prefix == "" {
+		return
+	}
+
+	path := []string{}
+	lineage := cmd.Lineage()
+	for i := len(lineage) - 2; i >= 0; i-- {
+		path = append(path, lineage[i].Name)
+	}
+
+	cmd.bindEnvPrefixFlags(prefix, path)
+}
+
+func (cmd *Command) bindEnvPrefixFlags(prefix string, path []string) {
+	tracef("binding flags to environment variables with prefix %[1]q (cmd=%[2]q)", prefix, cmd.Name)
+
+	for _, fl := range cmd.allFlags() {
+		ef, ok := fl.(envPrefixFlag)
+		if !ok || isBuiltinFlag(fl) || len(fl.Names()) == 0 {
+			continue
+		}
+
+		parts := append(append([]string{prefix}, path...), fl.Names()[0])
+		ef.bindEnvPrefix(envVarName(parts...))
+	}
+
+	for _, subCmd := range cmd.Commands {
+		subCmd.bindEnvPrefixFlags(prefix, append(append([]string{}, path...), subCmd.Name))
+	}
+}
+
+// envVarName joins the given parts into an upper snake case environment
+// variable name, e.g. ("MYAPP", "db", "host-name") becomes MYAPP_DB_HOST_NAME
+func envVarName(parts ...string) string {
+	sb := strings.Builder{}
+
+	for i, part := range parts {
+		if i > 0 {
+			sb.WriteByte('_')
+		}
+
+		for _, r := range part {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				sb.WriteRune(unicode.ToUpper(r))
+			} else {
+				sb.WriteByte('_')
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// lookupEnv looks up an environment variable in the Env of the closest
+// command which sets one, falling back to the process environment
+func (cmd *Command) lookupEnv(key string) (string, bool) {
+	for _, pCmd := range cmd.Lineage() {
+		if pCmd.Env != nil {
+			v, ok := pCmd.Env[key]
+			return v, ok
+		}
+	}
+
+	return os.LookupEnv(key)
+}
+
+// environ returns the Env of the closest command which sets one as
+// KEY=value pairs, falling back to the process environment
+func (cmd *Command) environ() []string {
+	for _, pCmd := range cmd.Lineage() {
+		if pCmd.Env != nil {
+			env := make([]string, 0, len(pCmd.Env))
+			for k, v := range pCmd.Env {
+				env = append(env, k+"="+v)
+			}
+			return env
+		}
+	}
+
+	return os.Environ()
+}
+
+// fileSystem returns the FS of the closest command which sets one, or nil
+// when files are read from the operating system
+func (cmd *Command) fileSystem() fs.FS {
+	for _, pCmd := range cmd.Lineage() {
+		if pCmd.FS != nil {
+			return pCmd.FS
+		}
+	}
+
+	return nil
+}
+
+// sourceCache returns the cache to parse files through. Commands with
+// their own Env or FS use caches which only live for the run of the root
+// command, as their files and variables differ from those of the process.
+func (cmd *Command) sourceCache(fc *fileCache) *fileCache {
+	isolated := false
+	for _, pCmd := range cmd.Lineage() {
+		if pCmd.Env != nil || pCmd.FS != nil {
+			isolated = true
+			break
+		}
+	}
+
+	if !isolated {
+		return fc
+	}
+
+	root := cmd.Root()
+	if root.sourceCaches == nil {
+		root.sourceCaches = map[*fileCache]*fileCache{}
+	}
+	if _, ok := root.sourceCaches[fc]; !ok {
+		root.sourceCaches[fc] = &fileCache{}
+	}
+
+	return root.sourceCaches[fc]
+}
+
+// commandFromContext returns the command running in the given context
+func commandFromContext(ctx context.Context) *Command {
+	cmd, _ := ctx.Value(commandContextKey).(*Command)
+	return cmd
+}
+
+// lookupEnvContext looks up an environment variable in the environment of
+// the command running in the given context
+func lookupEnvContext(ctx context.Context, key string) (string, bool) {
+	if cmd := commandFromContext(ctx); cmd != nil {
+		return cmd.lookupEnv(key)
+	}
+
+	return os.LookupEnv(key)
+}
+
+// applyContextFlag is an interface to enable passing the context and the
+// environment of the command to flags before they are applied
+type applyContextFlag interface {
+	setApplyContext(ctx context.Context, lookupEnv func(key string) (string, bool))
+}
+
+func (f *FlagBase[T, C, V]) setApplyContext(ctx context.Context, lookupEnv func(key string) (string, bool)) {
+	f.applyCtx = ctx
+	f.lookupEnv = lookupEnv
+}
+
+// envLookupFlag is an interface for flags which look up environment
+// variables in the environment of their command
+type envLookupFlag interface {
+	lookupEnvVar(key string) (string, bool)
+}
+
+// bindSourcesEnv returns the sources with the environment variable sources
+// bound to lookupEnv, which decides how they are described
+func bindSourcesEnv(sources []ValueSource, lookupEnv func(key string) (string, bool)) []ValueSource {
+	bound := make([]ValueSource, 0, len(sources))
+	for _, src := range sources {
+		if ev, ok := src.(*envVarValueSource); ok {
+			src = &envVarValueSource{Key: ev.Key, lookupEnv: lookupEnv}
+		}
+		bound = append(bound, src)
+	}
+
+	return bound
+}
+
+// lookupEnvVar looks up an environment variable in the environment of the
+// command the flag was applied to
+func (f *FlagBase[T, C, V]) lookupEnvVar(key string) (string, bool) {
+	if f.lookupEnv == nil {
+		return os.LookupEnv(key)
+	}
+
+	return f.lookupEnv(key)
+}
+
+// bindApplyContext passes the context and the environment of the command
+// to the flag
+func (cmd *Command) bindApplyContext(ctx context.Context, fl Flag) {
+	if af, ok := fl.(applyContextFlag); ok {
+		af.setApplyContext(ctx, cmd.lookupEnv)
+	}
+}
+
+// UnknownEnvVarsAction defines what happens with environment variables
+// which start with the root command's EnvPrefix but are claimed by no flag
+type UnknownEnvVarsAction int
+
+const (
+	// UnknownEnvVarsIgnore silently ignores unknown environment variables
+	UnknownEnvVarsIgnore UnknownEnvVarsAction = iota
+	// UnknownEnvVarsWarn prints a warning for every unknown environment
+	// variable to the root command's ErrWriter
+	UnknownEnvVarsWarn
+	// UnknownEnvVarsError fails the run when an unknown environment
+	// variable is found
+	UnknownEnvVarsError
+)
+
+// checkUnknownEnvVars scans the environment for variables with the root
+// command's EnvPrefix which are not claimed by any flag or argument of the
+// command tree
+func (cmd *Command) checkUnknownEnvVars() error {
+	prefix := strings.TrimRight(cmd.EnvPrefix, "_")
+	if prefix == "" || cmd.UnknownEnvVars == UnknownEnvVarsIgnore {
+		return nil
+	}
+
+	claimed := map[string]bool{}
+	cmd.collectEnvVars(claimed)
+
+	if name := cmd.profileEnvVar(); name != "" {
+		claimed[name] = true
+	}
+
+	candidates := []string{}
+	for name := range claimed {
+		if strings.HasPrefix(name, prefix+"_") {
+			candidates = append(candidates, name)
+		}
+	}
+
+	unknown := []string{}
+	for _, kv := range cmd.environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, prefix+"_") && !claimed[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	tracef("found unknown environment variables %[1]q (cmd=%[2]q)", unknown, cmd.Name)
+
+	err := &errUnknownEnvVars{names: unknown, suggestions: map[string]string{}}
+	for _, name := range unknown {
+		err.suggestions[name] = suggestName(candidates, name)
+	}
+
+	if cmd.UnknownEnvVars == UnknownEnvVarsError {
+		return err
+	}
+
+	for _, msg := range err.messages() {
+		_, _ = fmt.Fprintf(cmd.ErrWriter, "Warning: %s\n", msg)
+	}
+
+	return nil
+}
+
+func (cmd *Command) collectEnvVars(claimed map[string]bool) {
+	for _, fl := range cmd.allFlags() {
+		if df, ok := fl.(DocGenerationFlag); ok {
+			for _, name := range df.GetEnvVars() {
+				claimed[strings.TrimSpace(name)] = true
+			}
+		}
+	}
+
+	for _, arg := range cmd.Arguments {
+		if ea, ok := arg.(envVarArgument); ok {
+			for _, name := range ea.GetEnvVars() {
+				claimed[strings.TrimSpace(name)] = true
+			}
+		}
+	}
+
+	for _, subCmd := range cmd.Commands {
+		subCmd.collectEnvVars(claimed)
+	}
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func buildEnvPrefixTestCommand() *Command {
+	return &Command{
+		Name:      "app",
+		EnvPrefix: "MYAPP",
+		Flags: []Flag{
+			&StringFlag{Name: "log-level", Usage: "how chatty to be"},
+			&StringFlag{Name: "token", Sources: EnvVars("APP_TOKEN")},
+			&StringFlag{Name: "local", DisableEnvPrefix: true},
+		},
+		Commands: []*Command{
+			{
+				Name: "db",
+				Commands: []*Command{
+					{
+						Name: "migrate",
+						Flags: []Flag{
+							&IntFlag{Name: "steps", Usage: "number of steps"},
+						},
+					},
+				},
+				Flags: []Flag{
+					&StringFlag{Name: "host", Usage: "database host"},
+				},
+			},
+		},
+	}
+}
+
+func TestEnvVarName(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("MYAPP_LOG_LEVEL", envVarName("MYAPP", "log-level"))
+	r.Equal("MYAPP_DB_MIGRATE_STEPS", envVarName("MYAPP", "db", "migrate", "steps"))
+	r.Equal("MYAPP_DRY_RUN", envVarName("MYAPP", "dry.run"))
+}
+
+func TestCommand_EnvPrefix(t *testing.T) {
+	t.Setenv("MYAPP_LOG_LEVEL", "debug")
+	t.Setenv("MYAPP_TOKEN", "ignored")
+	t.Setenv("APP_TOKEN", "secret")
+	t.Setenv("MYAPP_LOCAL", "ignored")
+	t.Setenv("MYAPP_DB_HOST", "db.example.com")
+	t.Setenv("MYAPP_DB_MIGRATE_STEPS", "3")
+
+	var (
+		logLevel, token, local, host string
+		steps                        int64
+	)
+
+	cmd := buildEnvPrefixTestCommand()
+	migrate := cmd.Commands[0].Commands[0]
+	migrate.Action = func(_ context.Context, cmd *Command) error {
+		logLevel = cmd.String("log-level")
+		token = cmd.String("token")
+		local = cmd.String("local")
+		host = cmd.String("host")
+		steps = cmd.Int("steps")
+		return nil
+	}
+
+	r := require.New(t)
+	r.NoError(cmd.Run(buildTestContext(t), []string{"app", "db", "migrate"}))
+
+	r.Equal("debug", logLevel)
+	r.Equal("secret", token)
+	r.Equal("", local)
+	r.Equal("db.example.com", host)
+	r.Equal(int64(3), steps)
+
+	r.Equal([]string{"MYAPP_LOG_LEVEL"}, cmd.Flags[0].(*StringFlag).GetEnvVars())
+	r.Equal([]string{"APP_TOKEN"}, cmd.Flags[1].(*StringFlag).GetEnvVars())
+	r.Empty(cmd.Flags[2].(*StringFlag).GetEnvVars())
+}
+
+func TestCommand_EnvPrefix_Help(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cmd := buildEnvPrefixTestCommand()
+	cmd.Writer = buf
+
+	r := require.New(t)
+	r.NoError(cmd.Run(buildTestContext(t), []string{"app", "db", "--help"}))
+	r.Contains(buf.String(), "--host value  database host [$MYAPP_DB_HOST]")
+}
+
+func TestCommand_EnvPrefix_FishCompletion(t *testing.T) {
+	cmd := buildEnvPrefixTestCommand()
+
+	res, err := cmd.ToFishCompletion()
+
+	r := require.New(t)
+	r.NoError(err)
+	r.Contains(res, "-l log-level -r -d 'how chatty to be [$MYAPP_LOG_LEVEL]'")
+	r.Contains(res, "-l token -r -d '[$APP_TOKEN]'")
+	r.Contains(res, "-l steps -r -d 'number of steps [$MYAPP_DB_MIGRATE_STEPS]'")
+}
+
+func TestCommand_UnknownEnvVars(t *testing.T) {
+	t.Setenv("MYAPP_DB_HSOT", "db.example.com")
+	t.Setenv("MYAPP_LOG_LEVEL", "debug")
+	t.Setenv("MYAPPLICATION", "unrelated")
+
+	t.Run("ignore", func(t *testing.T) {
+		errBuf := &bytes.Buffer{}
+		cmd := buildEnvPrefixTestCommand()
+		cmd.ErrWriter = errBuf
+		cmd.Action = func(context.Context, *Command) error { return nil }
+
+		r := require.New(t)
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app"}))
+		r.Empty(errBuf.String())
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		errBuf := &bytes.Buffer{}
+		actionCalled := false
+		cmd := buildEnvPrefixTestCommand()
+		cmd.ErrWriter = errBuf
+		cmd.UnknownEnvVars = UnknownEnvVarsWarn
+		cmd.Action = func(context.Context, *Command) error {
+			actionCalled = true
+			return nil
+		}
+
+		r := require.New(t)
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app"}))
+		r.True(actionCalled)
+		r.Equal("Warning: unknown environment variable \"MYAPP_DB_HSOT\". Did you mean \"MYAPP_DB_HOST\"?\n", errBuf.String())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Setenv("MYAPP_LOG_LEVLE", "x")
+
+		actionCalled := false
+		cmd := buildEnvPrefixTestCommand()
+		cmd.UnknownEnvVars = UnknownEnvVarsError
+		cmd.Action = func(context.Context, *Command) error {
+			actionCalled = true
+			return nil
+		}
+
+		r := require.New(t)
+		err := cmd.Run(buildTestContext(t), []string{"app"})
+		r.EqualError(err, "unknown environment variable \"MYAPP_DB_HSOT\". Did you mean \"MYAPP_DB_HOST\"?\n"+
+			"unknown environment variable \"MYAPP_LOG_LEVLE\". Did you mean \"MYAPP_LOG_LEVEL\"?")
+		r.False(actionCalled)
+	})
+
+	t.Run("argument sources", func(t *testing.T) {
+		cmd := &Command{
+			Name:           "app",
+			EnvPrefix:      "MYAPP",
+			UnknownEnvVars: UnknownEnvVarsError,
+			Env:            map[string]string{"MYAPP_SRC": "in.txt"},
+			Arguments: []Argument{
+				&StringArg{Name: "src", Min: 1, Max: 1, Sources: EnvVars("MYAPP_SRC")},
+			},
+			Action: func(context.Context, *Command) error { return nil },
+		}
+
+		r := require.New(t)
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app"}))
+		r.Equal("in.txt", cmd.StringArg("src"))
+	})
+}
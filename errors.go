@@ -200,6 +200,173 @@ func handleMultiError(multiErr MultiError) int {
 	return code
 }
 
+type errUnknownEnvVars struct {
+	names       []string
+	suggestions map[string]string
+}
+
+func (e *errUnknownEnvVars) messages() []string {
+	msgs := make([]string, 0, len(e.names))
+	for _, name := range e.names {
+		msg := fmt.Sprintf("unknown environment variable %q", name)
+		if suggestion := e.suggestions[name]; suggestion != "" {
+			msg += ". " + fmt.Sprintf(SuggestDidYouMeanTemplate, suggestion)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func (e *errUnknownEnvVars) Error() string {
+	return strings.Join(e.messages(), "\n")
+}
+
+// SourceLookupError is returned when a ValueSource of a flag fails to look
+// up the value of the flag
+type SourceLookupError struct {
+	Flag   string      // name of the flag
+	Source ValueSource // source which failed
+	Err    error       // underlying error
+}
+
+func (e *SourceLookupError) Error() string {
+	return fmt.Sprintf("could not look up flag %[1]s from %[2]s: %[3]v", e.Flag, e.Source, e.Err)
+}
+
+func (e *SourceLookupError) Unwrap() error {
+	return e.Err
+}
+
+// ArgumentCountError is returned when fewer values than the minimum of an
+// argument are given
+type ArgumentCountError struct {
+	Name  string // name of the argument
+	Given int    // number of values given
+	Min   int    // minimum number of values
+}
+
+func (e *ArgumentCountError) Error() string {
+	return fmt.Sprintf("sufficient count of arg %[1]s not provided, given %[2]d expected %[3]d", e.Name, e.Given, e.Min)
+}
+
+// ArgumentValueError is returned when a value given for an argument cannot
+// be parsed, is not one of its Choices or fails its Validator
+type ArgumentValueError struct {
+	Name  string // name of the argument
+	Value string // value as given
+	Err   error  // underlying error
+}
+
+func (e *ArgumentValueError) Error() string {
+	return fmt.Sprintf("invalid value %[1]q for argument %[2]s: %[3]v", e.Value, e.Name, e.Err)
+}
+
+func (e *ArgumentValueError) Unwrap() error {
+	return e.Err
+}
+
+// ArgumentDefinitionError is returned when an argument is declared with
+// occurrences which cannot be satisfied
+type ArgumentDefinitionError struct {
+	Name string // name of the argument
+	Msg  string // description of the problem
+}
+
+func (e *ArgumentDefinitionError) Error() string {
+	return fmt.Sprintf("invalid definition of argument %[1]s: %[2]s", e.Name, e.Msg)
+}
+
+type errNotAChoice struct {
+	choices  []string
+	provided string
+}
+
+func (e *errNotAChoice) Error() string {
+	quoted := make([]string, 0, len(e.choices))
+	for _, choice := range e.choices {
+		quoted = append(quoted, fmt.Sprintf("%q", choice))
+	}
+
+	msg := "must be one of " + strings.Join(quoted, ", ")
+	if suggestion := suggestName(e.choices, e.provided); suggestion != "" {
+		msg += ". " + fmt.Sprintf(SuggestDidYouMeanTemplate, suggestion)
+	}
+	return msg
+}
+
+// UnknownFlagError is returned by the FlagParserGNU parser for a flag which
+// is not defined
+type UnknownFlagError struct {
+	Flag string // flag as given, e.g. --verbose or -v
+}
+
+func (e *UnknownFlagError) Error() string {
+	return fmt.Sprintf("flag provided but not defined: %[1]s", e.Flag)
+}
+
+// AmbiguousFlagError is returned with PrefixMatchFlags for an abbreviated
+// long flag which is a prefix of the names of several flags
+type AmbiguousFlagError struct {
+	Flag       string   // flag as given, e.g. --verb
+	Candidates []string // flags starting with it, e.g. --verbose and --verbosity
+}
+
+func (e *AmbiguousFlagError) Error() string {
+	return fmt.Sprintf("ambiguous flag %[1]s could be %[2]s", e.Flag, strings.Join(e.Candidates, ", "))
+}
+
+// MissingFlagValueError is returned by the FlagParserGNU parser for a flag
+// taking a value which is the last argument
+type MissingFlagValueError struct {
+	Flag string // flag as given, e.g. --output or -o
+}
+
+func (e *MissingFlagValueError) Error() string {
+	return fmt.Sprintf("flag needs an argument: %[1]s", e.Flag)
+}
+
+// InvalidFlagValueError is returned by the FlagParserGNU parser when the
+// value given for a flag is rejected by the flag
+type InvalidFlagValueError struct {
+	Flag  string // flag as given, e.g. --count or -n
+	Value string // value as given
+	Err   error  // underlying error
+}
+
+func (e *InvalidFlagValueError) Error() string {
+	return fmt.Sprintf("invalid value %[1]q for flag %[2]s: %[3]v", e.Value, e.Flag, e.Err)
+}
+
+func (e *InvalidFlagValueError) Unwrap() error {
+	return e.Err
+}
+
+// FlagSyntaxError is returned by the FlagParserGNU parser for an argument
+// which is not a well-formed flag, e.g. a long flag given with one dash
+type FlagSyntaxError struct {
+	Arg string // argument as given
+	Msg string // optional hint on the expected syntax
+}
+
+func (e *FlagSyntaxError) Error() string {
+	if e.Msg == "" {
+		return fmt.Sprintf("bad flag syntax: %[1]s", e.Arg)
+	}
+	return fmt.Sprintf("bad flag syntax: %[1]s, %[2]s", e.Arg, e.Msg)
+}
+
+// ArgsSyntaxError is returned for malformed arguments read with
+// ReadArgsFromStdin or from a response file, e.g. an unterminated quote
+type ArgsSyntaxError struct {
+	Line   int    // 1-based line number of the offending character
+	Column int    // 1-based column of the offending character, counted in runes
+	Msg    string // description of the problem
+}
+
+func (e *ArgsSyntaxError) Error() string {
+	return fmt.Sprintf("%[1]d:%[2]d: %[3]s", e.Line, e.Column, e.Msg)
+}
+
 type typeError[T any] struct {
 	other any
 }
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expand expands the given value if the flag opted in to expansion
+func (f *FlagBase[T, C, V]) expand(val string) (string, error) {
+	if !f.ExpandEnv {
+		return val, nil
+	}
+
+	return expandValue(val, f.lookupEnvVar)
+}
+
+// expandDefault expands the default value of string and string slice flags
+func (f *FlagBase[T, C, V]) expandDefault() (T, error) {
+	if !f.ExpandEnv {
+		return f.Value, nil
+	}
+
+	switch v := any(f.Value).(type) {
+	case string:
+		s, err := f.expand(v)
+		if err != nil {
+			return f.Value, err
+		}
+		return any(s).(T), nil
+	case []string:
+		expanded := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := f.expand(item)
+			if err != nil {
+				return f.Value, err
+			}
+			expanded = append(expanded, s)
+		}
+		return any(expanded).(T), nil
+	}
+
+	return f.Value, nil
+}
+
+// expandValue expands $VAR, ${VAR} and ${VAR:-default} references using
+// lookupEnv, and a leading ~ to the home directory. The default of a
+// ${VAR:-default} reference is used when VAR is unset or empty and may
+// contain references itself. $$ is replaced by a literal $.
+func expandValue(s string, lookupEnv func(key string) (string, bool)) (string, error) {
+	if s == "~" || strings.HasPrefix(s, "~/") || strings.HasPrefix(s, "~"+string(os.PathSeparator)) {
+		home, ok := lookupEnv("HOME")
+		if !ok || home == "" {
+			home, ok = lookupEnv("USERPROFILE")
+		}
+		if !ok || home == "" {
+			return "", errors.New("cannot expand \"~\": home directory is not set")
+		}
+		s = home + s[1:]
+	}
+
+	return expandVars(s, lookupEnv)
+}
+
+func expandVars(s string, lookupEnv func(key string) (string, bool)) (string, error) {
+	sb := strings.Builder{}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		switch next := s[i+1]; {
+		case next == '$':
+			sb.WriteByte('$')
+			i++
+
+		case next == '{':
+			end := matchingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference %[1]q", s[i:])
+			}
+
+			ref := s[i+2 : end]
+			name, def, hasDef := strings.Cut(ref, ":-")
+			if !isEnvVarName(name) {
+				return "", fmt.Errorf("invalid variable reference %[1]q", s[i:end+1])
+			}
+
+			v, ok := lookupEnv(name)
+			if hasDef && (!ok || v == "") {
+				var err error
+				if v, err = expandVars(def, lookupEnv); err != nil {
+					return "", err
+				}
+			}
+
+			sb.WriteString(v)
+			i = end
+
+		case next == '_' || isASCIILetter(next):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || isASCIILetter(s[j]) || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+
+			v, _ := lookupEnv(s[i+1 : j])
+			sb.WriteString(v)
+			i = j - 1
+
+		default:
+			sb.WriteByte('$')
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// matchingBrace returns the index of the brace closing the reference
+// starting at the given index, accounting for nested references
+func matchingBrace(s string, start int) int {
+	depth := 1
+
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func isEnvVarName(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' && !isASCIILetter(s[i]) && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
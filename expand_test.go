@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandValue(t *testing.T) {
+	env := map[string]string{
+		"HOME":  "/home/user",
+		"CACHE": "/var/cache",
+		"EMPTY": "",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	tests := []struct {
+		in  string
+		out string
+		err string
+	}{
+		{in: "plain", out: "plain"},
+		{in: "$HOME/.app", out: "/home/user/.app"},
+		{in: "${CACHE}/app", out: "/var/cache/app"},
+		{in: "${MISSING}/app", out: "/app"},
+		{in: "${MISSING:-/tmp}/app", out: "/tmp/app"},
+		{in: "${EMPTY:-${CACHE}}/app", out: "/var/cache/app"},
+		{in: "${CACHE:-/tmp}/app", out: "/var/cache/app"},
+		{in: "~", out: "/home/user"},
+		{in: "~/app", out: "/home/user/app"},
+		{in: "a~/app", out: "a~/app"},
+		{in: "~user/app", out: "~user/app"},
+		{in: "$$HOME costs $5 $", out: "$HOME costs $5 $"},
+		{in: "${CACHE", err: `unterminated variable reference "${CACHE"`},
+		{in: "${1X}", err: `invalid variable reference "${1X}"`},
+		{in: "${}", err: `invalid variable reference "${}"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			out, err := expandValue(test.in, lookupEnv)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.out, out)
+		})
+	}
+
+	_, err := expandValue("~/app", func(string) (string, bool) { return "", false })
+	require.EqualError(t, err, `cannot expand "~": home directory is not set`)
+}
+
+func TestCommand_ExpandEnv(t *testing.T) {
+	buildCommand := func(out *bytes.Buffer) *Command {
+		return &Command{
+			Name:   "app",
+			Writer: out,
+			Env:    map[string]string{"HOME": "/home/user", "XDG_CACHE_HOME": "/cache", "APP_DATA": "${HOME}/data"},
+			Flags: []Flag{
+				&StringFlag{Name: "home", Value: "$HOME/.app", ExpandEnv: true},
+				&StringFlag{Name: "cache-dir", ExpandEnv: true},
+				&StringFlag{Name: "data-dir", Sources: EnvVars("APP_DATA"), ExpandEnv: true},
+				&StringSliceFlag{Name: "path", Value: []string{"~/bin", "/usr/bin"}, ExpandEnv: true},
+				&StringFlag{Name: "raw", Value: "$HOME"},
+			},
+			Commands: []*Command{
+				{Name: "sub"},
+			},
+		}
+	}
+
+	t.Run("values", func(t *testing.T) {
+		r := require.New(t)
+		cmd := buildCommand(&bytes.Buffer{})
+
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--cache-dir", "${XDG_CACHE_HOME}/app", "sub"}))
+		r.Equal("/home/user/.app", cmd.String("home"))
+		r.Equal("/cache/app", cmd.String("cache-dir"))
+		r.Equal("/home/user/data", cmd.String("data-dir"))
+		r.Equal([]string{"/home/user/bin", "/usr/bin"}, cmd.StringSlice("path"))
+		r.Equal("$HOME", cmd.String("raw"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cmd := buildCommand(&bytes.Buffer{})
+		cmd.ErrWriter = &bytes.Buffer{}
+
+		err := cmd.Run(buildTestContext(t), []string{"app", "--cache-dir", "${XDG_CACHE_HOME"})
+		require.EqualError(t, err, `invalid value "${XDG_CACHE_HOME" for flag -cache-dir: unterminated variable reference "${XDG_CACHE_HOME"`)
+	})
+
+	t.Run("help shows unexpanded default", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := buildCommand(out)
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "--help"}))
+		require.Contains(t, out.String(), `--home value                   (default: "$HOME/.app")`)
+		require.Contains(t, out.String(), `(default: "~/bin", "/usr/bin")`)
+	})
+}
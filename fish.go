@@ -11,6 +11,8 @@ import (
 // ToFishCompletion creates a fish completion string for the `*App`
 // The function errors if either parsing or writing of the string fails.
 func (cmd *Command) ToFishCompletion() (string, error) {
+	cmd.setupEnvPrefix()
+
 	var w bytes.Buffer
 	if err := cmd.writeFishCompletionTemplate(&w); err != nil {
 		return "", err
@@ -142,9 +144,18 @@ func (cmd *Command) prepareFishFlags(flags []Flag, previousCommands []string) []
 				completion.WriteString(" -r")
 			}
 
-			if flag.GetUsage() != "" {
+			usage := flag.GetUsage()
+			if sf, ok := f.(DocGenerationSourcesFlag); ok {
+				if sources := sf.GetValueSources(); len(sources) > 0 {
+					usage = strings.TrimSpace(FlagSourcesHinter(bindSourcesEnv(sources, cmd.lookupEnv), usage))
+				}
+			} else if envVars := flag.GetEnvVars(); len(envVars) > 0 {
+				usage = strings.TrimSpace(FlagEnvHinter(envVars, usage))
+			}
+
+			if usage != "" {
 				completion.WriteString(fmt.Sprintf(" -d '%s'",
-					escapeSingleQuotes(flag.GetUsage())))
+					escapeSingleQuotes(usage)))
 			}
 		}
 
@@ -40,6 +40,38 @@ var VersionFlag Flag = &BoolFlag{
 	Usage:   "print the version",
 }
 
+// DebugFlagsFlag prints the effective value and origin of every flag
+// when EnableDebugFlags is set on the root command.
+// Set to nil to disable the flag.
+var DebugFlagsFlag Flag = &BoolFlag{
+	Name:  "debug-flags",
+	Usage: "print the effective flag values and where they came from",
+}
+
+// PrintConfigFlag prints the effective configuration in the given format
+// when EnablePrintConfig is set on the root command.
+// Set to nil to disable the flag.
+var PrintConfigFlag Flag = &StringFlag{
+	Name:  "print-config",
+	Usage: "print the effective configuration as `FORMAT` (json, env or args)",
+}
+
+// ConfigFlag sets the configuration file to load flag values from instead
+// of searching ConfigPaths when EnableConfigFile is set on the root command.
+// Set to nil to disable the flag.
+var ConfigFlag Flag = &StringFlag{
+	Name:  "config",
+	Usage: "load configuration from `FILE`",
+}
+
+// ProfileFlag selects the named profile of the config files when
+// EnableProfiles is set on the root command.
+// Set to nil to disable the flag.
+var ProfileFlag Flag = &StringFlag{
+	Name:  "profile",
+	Usage: "use the configuration `PROFILE`",
+}
+
 // HelpFlag prints the help for all commands and subcommands.
 // Set to nil to disable the flag.  The subcommand
 // will still be added unless HideHelp or HideHelpCommand is set to true.
@@ -70,6 +102,11 @@ var FlagEnvHinter FlagEnvHintFunc = withEnvHint
 // details. This is used by the default FlagStringer.
 var FlagFileHinter FlagFileHintFunc = withFileHint
 
+// FlagSourcesHinter annotates flag help message with the value sources of
+// the flag in precedence order. This is used by the default FlagStringer
+// and by fish completion descriptions.
+var FlagSourcesHinter FlagSourcesHintFunc = withSourcesHint
+
 // FlagsByName is a slice of Flag.
 type FlagsByName []Flag
 
@@ -137,6 +174,14 @@ type DocGenerationFlag interface {
 	GetEnvVars() []string
 }
 
+// DocGenerationSourcesFlag is an interface for flags whose value sources
+// are listed in help and documentation output
+type DocGenerationSourcesFlag interface {
+	// GetValueSources returns the value sources of the flag in
+	// precedence order
+	GetValueSources() []ValueSource
+}
+
 // DocGenerationSliceFlag extends DocGenerationFlag for slice/map based flags.
 type DocGenerationMultiValueFlag interface {
 	DocGenerationFlag
@@ -293,8 +338,14 @@ func defaultEnvFormat(envVars []string) string {
 }
 
 func withEnvHint(envVars []string, str string) string {
+	return withEnvHintEnv(os.LookupEnv, envVars, str)
+}
+
+// withEnvHintEnv annotates the flag help message with the environment
+// variables, formatted for the shell detected from lookupEnv
+func withEnvHintEnv(lookupEnv func(key string) (string, bool), envVars []string, str string) string {
 	envText := ""
-	if runtime.GOOS != "windows" || os.Getenv("PSHOME") != "" {
+	if !isWindowsCmd(lookupEnv) {
 		envText = defaultEnvFormat(envVars)
 	} else {
 		envText = envFormat(envVars, "%", "%, %", "%")
@@ -302,6 +353,28 @@ func withEnvHint(envVars []string, str string) string {
 	return str + envText
 }
 
+// isWindowsCmd returns true when environment variables are referenced
+// with the syntax of the Windows command prompt, detected from lookupEnv
+func isWindowsCmd(lookupEnv func(key string) (string, bool)) bool {
+	pshome, _ := lookupEnv("PSHOME")
+	return runtime.GOOS == "windows" && pshome == ""
+}
+
+// withSourcesHint annotates the flag help message with the descriptions of
+// the value sources of the flag
+func withSourcesHint(sources []ValueSource, str string) string {
+	if len(sources) == 0 {
+		return str
+	}
+
+	descriptions := make([]string, 0, len(sources))
+	for _, src := range sources {
+		descriptions = append(descriptions, describeValueSource(src))
+	}
+
+	return str + " [" + strings.Join(descriptions, ", ") + "]"
+}
+
 func FlagNames(name string, aliases []string) []string {
 	var ret []string
 
@@ -356,7 +429,17 @@ func stringifyFlag(f Flag) string {
 		pn = pn + " [ " + pn + " ]"
 	}
 
-	return withEnvHint(df.GetEnvVars(), fmt.Sprintf("%s\t%s", pn, usageWithDefault))
+	lookupEnv := os.LookupEnv
+	if ef, ok := f.(envLookupFlag); ok {
+		lookupEnv = ef.lookupEnvVar
+	}
+
+	str := fmt.Sprintf("%s\t%s", pn, usageWithDefault)
+	if sf, ok := f.(DocGenerationSourcesFlag); ok {
+		return FlagSourcesHinter(bindSourcesEnv(sf.GetValueSources(), lookupEnv), str)
+	}
+
+	return withEnvHintEnv(lookupEnv, df.GetEnvVars(), str)
 }
 
 func hasFlag(flags []Flag, fl Flag) bool {
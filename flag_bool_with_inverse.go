@@ -41,6 +41,20 @@ func (s *BoolWithInverseFlag) Value() bool {
 	return *s.posDest
 }
 
+// GetOrigin returns where the current value of either the positive or
+// the negative flag came from, preferring the one set most explicitly
+func (s *BoolWithInverseFlag) GetOrigin() FlagOrigin {
+	if s.positiveFlag == nil {
+		return s.BoolFlag.GetOrigin()
+	}
+
+	if neg := s.negativeFlag.GetOrigin(); neg.Kind > s.positiveFlag.GetOrigin().Kind {
+		return neg
+	}
+
+	return s.positiveFlag.GetOrigin()
+}
+
 func (s *BoolWithInverseFlag) RunAction(ctx context.Context, cmd *Command) error {
 	if *s.negDest && *s.posDest {
 		return fmt.Errorf("cannot set both flags `--%s` and `--%s`", s.positiveFlag.Name, s.negativeFlag.Name)
@@ -96,12 +96,31 @@ type FlagBase[T any, C any, VC ValueCreator[T, C]] struct {
 
 	Validator func(T) error // custom function to validate this flag value
 
+	DisableEnvPrefix bool // whether to skip binding an environment variable derived from the root command's EnvPrefix
+
+	DisableConfig bool // whether to skip binding the flag to a key in the root command's config files
+
+	ExpandEnv bool // whether to expand $VAR, ${VAR}, ${VAR:-default} and a leading ~ in values and defaults
+
+	Sensitive bool // whether to mask the value of this flag when printing the configuration
+
+	Merge MergePolicy // how slice and map flags combine the values of their sources
+
 	// unexported fields for internal use
-	count      int   // number of times the flag has been set
-	hasBeenSet bool  // whether the flag has been set from env or file
-	applied    bool  // whether the flag has been applied to a flag set already
-	creator    VC    // value creator for this flag type
-	value      Value // value representing this flag's value
+	count      int        // number of times the flag has been set
+	hasBeenSet bool       // whether the flag has been set from env or file
+	applied    bool       // whether the flag has been applied to a flag set already
+	creator    VC         // value creator for this flag type
+	value      Value      // value representing this flag's value
+	origin     FlagOrigin // where the current value of the flag came from
+
+	derivedEnvVar string             // environment variable derived from the root command's EnvPrefix
+	configSource  *configValueSource // key in the root command's config files
+
+	applyCtx  context.Context                 // context of the command the flag is applied to
+	lookupEnv func(key string) (string, bool) // environment of the command the flag is applied to
+
+	deferredSources *ValueSourceChain // sources to look up once the command line is parsed
 }
 
 // GetValue returns the flags value as string representation and an empty
@@ -122,29 +141,66 @@ func (f *FlagBase[T, C, V]) Apply(set *flag.FlagSet) error {
 	// flag can be applied to different flag sets multiple times while still
 	// keeping the env set.
 	if !f.applied || !f.Persistent {
-		newVal := f.Value
-
-		if val, source, found := f.Sources.LookupWithSource(); found {
-			tmpVal := f.creator.Create(f.Value, new(T), f.Config)
-			if val != "" || reflect.TypeOf(f.Value).Kind() == reflect.String {
-				if err := tmpVal.Set(val); err != nil {
-					return fmt.Errorf(
-						"could not parse %[1]q as %[2]T value from %[3]s for flag %[4]s: %[5]s",
-						val, f.Value, source, f.Name, err,
-					)
-				}
-			} else if val == "" && reflect.TypeOf(f.Value).Kind() == reflect.Bool {
-				val = "false"
-				if err := tmpVal.Set(val); err != nil {
-					return fmt.Errorf(
-						"could not parse %[1]q as %[2]T value from %[3]s for flag %[4]s: %[5]s",
-						val, f.Value, source, f.Name, err,
-					)
-				}
+		newVal, err := f.expandDefault()
+		if err != nil {
+			return fmt.Errorf("could not expand default value %[1]v for flag %[2]s: %[3]w", f.Value, f.Name, err)
+		}
+		// forget the origin recorded in a previous run
+		f.origin = FlagOrigin{}
+
+		sources := f.Sources
+		if f.configSource != nil {
+			configSources := []ValueSource{f.configSource}
+			if f.Merge != MergeFirstWins {
+				// every config file contributes to merged values
+				configSources = f.configSource.perFile()
 			}
 
-			newVal = tmpVal.Get().(T)
-			f.hasBeenSet = true
+			// config files have lower precedence than any declared source
+			sources = ValueSourceChain{Chain: append(append([]ValueSource{}, f.Sources.Chain...), configSources...)}
+		}
+
+		ctx := f.applyCtx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if err := f.checkMergePolicy(); err != nil {
+			return err
+		}
+
+		if f.Merge != MergeFirstWins {
+			// every source contributes to merged values, so none is deferred
+			f.deferredSources = nil
+
+			merged, used, err := f.mergeSources(ctx, sources)
+			if err != nil {
+				return err
+			}
+
+			if len(used) > 0 {
+				newVal = merged
+				f.hasBeenSet = true
+				f.origin = FlagOrigin{Kind: FlagOriginSource, Source: used[0], Sources: used}
+			}
+		} else {
+			// sources which must only be looked up when the value is needed,
+			// such as commands, are resolved after the command line is parsed
+			sources, f.deferredSources = splitDeferredSources(sources)
+
+			val, source, found, err := sources.LookupWithSourceContext(ctx)
+			if err != nil {
+				return &SourceLookupError{Flag: f.Name, Source: source, Err: err}
+			}
+
+			if found {
+				if newVal, err = f.parseSourceValue(val, source); err != nil {
+					return err
+				}
+				f.deferredSources = nil
+				f.hasBeenSet = true
+				f.origin = FlagOrigin{Kind: FlagOriginSource, Source: source}
+			}
 		}
 
 		if f.Destination == nil {
@@ -153,6 +209,11 @@ func (f *FlagBase[T, C, V]) Apply(set *flag.FlagSet) error {
 			f.value = f.creator.Create(newVal, f.Destination, f.Config)
 		}
 
+		// values given on the command line are added to merged values
+		if rv, ok := f.value.(retainedValue); ok && len(f.origin.Sources) > 0 {
+			rv.retain()
+		}
+
 		// Validate the given default or values set from external sources as well
 		if f.Validator != nil {
 			if v, ok := f.value.Get().(T); !ok {
@@ -177,10 +238,15 @@ func (f *FlagBase[T, C, V]) Apply(set *flag.FlagSet) error {
 					return fmt.Errorf("cant duplicate this flag")
 				}
 				f.count++
+				val, err := f.expand(val)
+				if err != nil {
+					return err
+				}
 				if err := f.value.Set(val); err != nil {
 					return err
 				}
 				f.hasBeenSet = true
+				f.origin = FlagOrigin{Kind: FlagOriginCLI, Sources: f.origin.Sources}
 				if f.Validator != nil {
 					if v, ok := f.value.Get().(T); !ok {
 						return &typeError[T]{
@@ -201,6 +267,91 @@ func (f *FlagBase[T, C, V]) Apply(set *flag.FlagSet) error {
 	return nil
 }
 
+// appliedFlag is implemented by flags which remember being applied
+type appliedFlag interface {
+	resetApplied()
+}
+
+func (f *FlagBase[T, C, V]) resetApplied() {
+	f.applied = false
+}
+
+// parseSourceValue parses the value looked up from the given source
+func (f *FlagBase[T, C, V]) parseSourceValue(val string, source ValueSource) (T, error) {
+	expanded, err := f.expand(val)
+	if err != nil {
+		return f.Value, fmt.Errorf(
+			"could not expand %[1]q from %[2]s for flag %[3]s: %[4]w",
+			val, source, f.Name, err,
+		)
+	}
+	val = expanded
+
+	tmpVal := f.creator.Create(f.Value, new(T), f.Config)
+	if val == "" && reflect.TypeOf(f.Value).Kind() == reflect.Bool {
+		val = "false"
+	}
+	if val != "" || reflect.TypeOf(f.Value).Kind() == reflect.String {
+		if err := tmpVal.Set(val); err != nil {
+			return f.Value, fmt.Errorf(
+				"could not parse %[1]q as %[2]T value from %[3]s for flag %[4]s: %[5]s",
+				val, f.Value, source, f.Name, err,
+			)
+		}
+	}
+
+	return tmpVal.Get().(T), nil
+}
+
+// resolveDeferredSources looks up the sources deferred by Apply unless the
+// flag was set on the command line
+func (f *FlagBase[T, C, V]) resolveDeferredSources(ctx context.Context, set *flag.FlagSet) error {
+	sources := f.deferredSources
+	f.deferredSources = nil
+
+	if sources == nil || f.count > 0 {
+		return nil
+	}
+
+	val, source, found, err := sources.LookupWithSourceContext(ctx)
+	if err != nil {
+		return &SourceLookupError{Flag: f.Name, Source: source, Err: err}
+	}
+	if !found {
+		return nil
+	}
+
+	newVal, err := f.parseSourceValue(val, source)
+	if err != nil {
+		return err
+	}
+
+	if f.Destination == nil {
+		f.value = f.creator.Create(newVal, new(T), f.Config)
+	} else {
+		f.value = f.creator.Create(newVal, f.Destination, f.Config)
+	}
+
+	for _, name := range f.Names() {
+		if fl := set.Lookup(name); fl != nil {
+			if fv, ok := fl.Value.(*fnValue); ok {
+				fv.v = f.value
+			}
+		}
+	}
+
+	f.hasBeenSet = true
+	f.origin = FlagOrigin{Kind: FlagOriginSource, Source: source}
+
+	if f.Validator != nil {
+		if err := f.Validator(newVal); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // String returns a readable representation of this value (for usage defaults)
 func (f *FlagBase[T, C, V]) String() string {
 	return FlagStringer(f)
@@ -211,6 +362,16 @@ func (f *FlagBase[T, C, V]) IsSet() bool {
 	return f.hasBeenSet
 }
 
+// GetOrigin returns where the current value of the flag came from
+func (f *FlagBase[T, C, V]) GetOrigin() FlagOrigin {
+	return f.origin
+}
+
+// IsSensitive returns true if the value of the flag must be masked
+func (f *FlagBase[T, C, V]) IsSensitive() bool {
+	return f.Sensitive
+}
+
 // Names returns the names of the flag
 func (f *FlagBase[T, C, V]) Names() []string {
 	return FlagNames(f.Name, f.Aliases)
@@ -236,6 +397,17 @@ func (f *FlagBase[T, C, V]) GetUsage() string {
 	return f.Usage
 }
 
+// GetValueSources returns the value sources of the flag in precedence order,
+// ending with its key in the config files if it is bound to one
+func (f *FlagBase[T, C, V]) GetValueSources() []ValueSource {
+	sources := append([]ValueSource{}, f.Sources.Chain...)
+	if f.configSource != nil {
+		sources = append(sources, f.configSource)
+	}
+
+	return sources
+}
+
 // GetEnvVars returns the env vars for this flag
 func (f *FlagBase[T, C, V]) GetEnvVars() []string {
 	vals := []string{}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// MergePolicy defines how a slice or map flag combines the values of its
+// Sources. With any policy but MergeFirstWins every source is looked up
+// when the flag is applied, including commands run by ExecOutput.
+type MergePolicy int
+
+const (
+	// MergeFirstWins uses the value of the first source which resolves,
+	// which is replaced by values given on the command line
+	MergeFirstWins MergePolicy = iota
+	// MergeAppend concatenates the values of every source of a slice flag
+	// from the lowest precedence to the highest, i.e. from the last source
+	// in the chain to the first, followed by the values given on the
+	// command line
+	MergeAppend
+	// MergeMaps combines the entries of every source of a map flag. Earlier
+	// sources in the chain win for a key, and values given on the command
+	// line override all of them.
+	MergeMaps
+)
+
+func (p MergePolicy) String() string {
+	switch p {
+	case MergeAppend:
+		return "append"
+	case MergeMaps:
+		return "map-merge"
+	default:
+		return "first-wins"
+	}
+}
+
+// retainedValue is implemented by values which can keep their current
+// contents when first set on the command line instead of replacing them
+type retainedValue interface {
+	retain()
+}
+
+func (i *SliceBase[T, C, VC]) retain() {
+	i.hasBeenSet = true
+}
+
+func (i *MapBase[T, C, VC]) retain() {
+	i.hasBeenSet = true
+}
+
+// checkMergePolicy returns an error when the merge policy of the flag does
+// not fit its type
+func (f *FlagBase[T, C, V]) checkMergePolicy() error {
+	kind := reflect.TypeOf(f.Value).Kind()
+
+	switch {
+	case f.Merge == MergeFirstWins,
+		f.Merge == MergeAppend && kind == reflect.Slice,
+		f.Merge == MergeMaps && kind == reflect.Map:
+		return nil
+	}
+
+	return fmt.Errorf("merge policy %[1]s is not supported by flag %[2]s of type %[3]T", f.Merge, f.Name, f.Value)
+}
+
+// mergeSources combines the values of every source which resolves
+// according to the merge policy of the flag
+func (f *FlagBase[T, C, V]) mergeSources(ctx context.Context, sources ValueSourceChain) (T, []ValueSource, error) {
+	var (
+		merged   reflect.Value
+		used     []ValueSource
+		parseErr error
+	)
+
+	src, err := sources.lookupAllWithSourceContext(ctx, func(val string, src ValueSource) error {
+		v, err := f.parseSourceValue(val, src)
+		if err != nil {
+			parseErr = err
+			return err
+		}
+
+		rv := reflect.ValueOf(v)
+		switch {
+		case !merged.IsValid():
+			merged = rv
+		case f.Merge == MergeAppend:
+			// sources come in order of precedence, the lowest is appended first
+			merged = reflect.AppendSlice(rv, merged)
+		case f.Merge == MergeMaps:
+			iter := rv.MapRange()
+			for iter.Next() {
+				if !merged.MapIndex(iter.Key()).IsValid() {
+					merged.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+		}
+
+		used = append(used, src)
+		return nil
+	})
+
+	if parseErr != nil {
+		return f.Value, nil, parseErr
+	}
+	if err != nil {
+		return f.Value, nil, &SourceLookupError{Flag: f.Name, Source: src, Err: err}
+	}
+	if !merged.IsValid() {
+		return f.Value, nil, nil
+	}
+
+	return merged.Interface().(T), used, nil
+}
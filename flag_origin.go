@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"strings"
+)
+
+// FlagOriginKind describes what kind of input supplied the effective
+// value of a flag
+type FlagOriginKind int
+
+const (
+	// FlagOriginDefault is used when the flag holds its default Value
+	FlagOriginDefault FlagOriginKind = iota
+	// FlagOriginSource is used when the value was looked up from one of
+	// the flag's Sources
+	FlagOriginSource
+	// FlagOriginCLI is used when the value was given on the command line
+	FlagOriginCLI
+)
+
+func (k FlagOriginKind) String() string {
+	switch k {
+	case FlagOriginSource:
+		return "source"
+	case FlagOriginCLI:
+		return "cli"
+	default:
+		return "default"
+	}
+}
+
+// FlagOrigin describes where the effective value of a flag came from
+type FlagOrigin struct {
+	// Kind of input which supplied the value
+	Kind FlagOriginKind
+	// Source is the ValueSource which supplied the value, only
+	// set when Kind is FlagOriginSource
+	Source ValueSource
+	// Sources lists every ValueSource which contributed to the value in
+	// chain order, only set for flags merging the values of their sources
+	Sources []ValueSource
+}
+
+// String returns a readable representation of the origin, e.g.
+// `cli`, `default` or `environment variable "APP_TOKEN"`. Merged values
+// list every contributing source, e.g. `cli, environment variable "APP_LABELS"`.
+func (o FlagOrigin) String() string {
+	parts := []string{}
+	if o.Kind == FlagOriginCLI && len(o.Sources) > 0 {
+		parts = append(parts, o.Kind.String())
+	}
+	for _, src := range o.Sources {
+		parts = append(parts, src.String())
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, ", ")
+	}
+
+	if o.Kind == FlagOriginSource && o.Source != nil {
+		return o.Source.String()
+	}
+	return o.Kind.String()
+}
+
+// OriginFlag is an interface to enable detection of where the effective
+// value of a flag came from
+type OriginFlag interface {
+	// GetOrigin returns where the current value of the flag came from
+	GetOrigin() FlagOrigin
+}
+
+// FlagOrigin returns where the effective value of the flag corresponding
+// to `name` came from. Flags which do not implement OriginFlag are reported
+// as set from the command line when they were parsed, and as holding their
+// default otherwise.
+func (cmd *Command) FlagOrigin(name string) FlagOrigin {
+	fl := cmd.lookupFlag(name)
+	if fl == nil {
+		tracef("flag NOT found for origin of name %[1]q (cmd=%[2]q)", name, cmd.Name)
+		cmd.onInvalidFlag(context.TODO(), name)
+		return FlagOrigin{}
+	}
+
+	if of, ok := fl.(OriginFlag); ok {
+		return of.GetOrigin()
+	}
+
+	if cmd.IsSet(name) {
+		return FlagOrigin{Kind: FlagOriginCLI}
+	}
+
+	return FlagOrigin{}
+}
+
+func checkDebugFlags(cmd *Command) bool {
+	if DebugFlagsFlag == nil {
+		return false
+	}
+
+	for _, pCmd := range cmd.Lineage() {
+		if pCmd.flagSet == nil {
+			continue
+		}
+
+		for _, name := range DebugFlagsFlag.Names() {
+			if f := pCmd.flagSet.Lookup(name); f != nil {
+				if v, ok := f.Value.(flag.Getter).Get().(bool); ok && v {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func isBuiltinFlag(fl Flag) bool {
+	return fl == HelpFlag || fl == VersionFlag || fl == DebugFlagsFlag || fl == PrintConfigFlag || fl == ConfigFlag || fl == ProfileFlag || fl == GenerateShellCompletionFlag
+}
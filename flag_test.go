@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/require"
@@ -3008,3 +3010,194 @@ func TestStringMapFlagApply_Error(t *testing.T) {
 		t.Errorf("expected error, but got none")
 	}
 }
+
+func TestFlagMergePolicy(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/app/labels": {Data: []byte("team=core,env=prod")},
+		"etc/app/tags":   {Data: []byte("base")},
+	}
+
+	labelSources := func() ValueSourceChain {
+		return ValueSourceChain{Chain: append(EnvVars("APP_LABELS").Chain, Files("/etc/app/labels").Chain...)}
+	}
+	tagSources := func() ValueSourceChain {
+		return ValueSourceChain{Chain: append(EnvVars("APP_TAGS").Chain, Files("/etc/app/tags").Chain...)}
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		merge  MergePolicy
+		tags   []string
+		labels map[string]string
+		origin string
+	}{
+		{
+			name:   "first wins",
+			args:   []string{"app"},
+			tags:   []string{"user"},
+			labels: map[string]string{"env": "dev"},
+			origin: `environment variable "APP_TAGS"`,
+		},
+		{
+			name:   "first wins with cli",
+			args:   []string{"app", "--tag", "cli", "--label", "owner=me"},
+			tags:   []string{"cli"},
+			labels: map[string]string{"owner": "me"},
+			origin: "cli",
+		},
+		{
+			name:   "merged",
+			args:   []string{"app"},
+			merge:  MergeAppend,
+			tags:   []string{"base", "user"},
+			labels: map[string]string{"env": "dev", "team": "core"},
+			origin: `environment variable "APP_TAGS", file "/etc/app/tags"`,
+		},
+		{
+			name:   "merged with cli",
+			args:   []string{"app", "--tag", "cli", "--label", "env=test", "--label", "owner=me"},
+			merge:  MergeAppend,
+			tags:   []string{"base", "user", "cli"},
+			labels: map[string]string{"env": "test", "team": "core", "owner": "me"},
+			origin: `cli, environment variable "APP_TAGS", file "/etc/app/tags"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			labelMerge := MergeFirstWins
+			if test.merge != MergeFirstWins {
+				labelMerge = MergeMaps
+			}
+
+			cmd := &Command{
+				Name: "app",
+				Env:  map[string]string{"APP_TAGS": "user", "APP_LABELS": "env=dev"},
+				FS:   fsys,
+				Flags: []Flag{
+					&StringSliceFlag{Name: "tag", Value: []string{"default"}, Sources: tagSources(), Merge: test.merge},
+					&StringMapFlag{Name: "label", Sources: labelSources(), Merge: labelMerge},
+				},
+			}
+
+			r := require.New(t)
+			r.NoError(cmd.Run(buildTestContext(t), test.args))
+			r.Equal(test.tags, cmd.StringSlice("tag"))
+			r.Equal(test.labels, cmd.StringMap("label"))
+			r.Equal(test.origin, cmd.FlagOrigin("tag").String())
+		})
+	}
+
+	t.Run("config files", func(t *testing.T) {
+		cmd := &Command{
+			Name:             "app",
+			Env:              map[string]string{"APP_LABEL": "e"},
+			EnableConfigFile: true,
+			ConfigPaths:      []string{"user.json", "sys.json"},
+			FS: fstest.MapFS{
+				"user.json": {Data: []byte(`{"label": ["u"], "tier": {"region": "eu"}}`)},
+				"sys.json":  {Data: []byte(`{"label": ["s"], "tier": {"region": "us", "zone": "a"}}`)},
+			},
+			Flags: []Flag{
+				&StringSliceFlag{Name: "label", Sources: EnvVars("APP_LABEL"), Merge: MergeAppend},
+				&StringMapFlag{Name: "tier", Merge: MergeMaps},
+			},
+		}
+
+		r := require.New(t)
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--label", "c"}))
+		r.Equal([]string{"s", "u", "e", "c"}, cmd.StringSlice("label"))
+		r.Equal(map[string]string{"region": "eu", "zone": "a"}, cmd.StringMap("tier"))
+		r.Equal(`cli, environment variable "APP_LABEL", key "label" in config file "user.json", key "label" in config file "sys.json"`, cmd.FlagOrigin("label").String())
+	})
+
+	t.Run("unsupported policy", func(t *testing.T) {
+		cmd := &Command{
+			Name:      "app",
+			Writer:    io.Discard,
+			ErrWriter: io.Discard,
+			Flags:     []Flag{&IntFlag{Name: "port", Merge: MergeAppend}},
+		}
+
+		err := cmd.Run(buildTestContext(t), []string{"app"})
+		require.EqualError(t, err, "merge policy append is not supported by flag port of type int64")
+	})
+
+	t.Run("invalid source value", func(t *testing.T) {
+		cmd := &Command{
+			Name:      "app",
+			Writer:    io.Discard,
+			ErrWriter: io.Discard,
+			Env:       map[string]string{"APP_PORTS": "80,http"},
+			Flags:     []Flag{&IntSliceFlag{Name: "port", Sources: EnvVars("APP_PORTS"), Merge: MergeAppend}},
+		}
+
+		err := cmd.Run(buildTestContext(t), []string{"app"})
+		require.ErrorContains(t, err, `could not parse "80,http" as []int64 value from environment variable "APP_PORTS" for flag port`)
+	})
+}
+
+type undescribedValueSource struct{}
+
+func (*undescribedValueSource) Lookup() (string, bool) { return "", false }
+func (*undescribedValueSource) String() string         { return "the vault" }
+func (*undescribedValueSource) GoString() string       { return "&undescribedValueSource{}" }
+
+func TestFlagSourcesHint(t *testing.T) {
+	sources := ValueSourceChain{Chain: []ValueSource{
+		&envVarValueSource{Key: "APP_TOKEN"},
+		&fileValueSource{Path: "/etc/app/token"},
+		&dotEnvValueSource{Path: ".env", Key: "TOKEN"},
+		&iniValueSource{Path: "app.ini", Key: "auth.token"},
+		&jsonValueSource{Path: "app.json", Key: "auth.token"},
+		&execValueSource{Args: []string{"pass", "show", "app token"}},
+		&undescribedValueSource{},
+	}}
+
+	fl := &StringFlag{Name: "token", Usage: "API token", Sources: sources}
+	require.Equal(t,
+		"--token value\tAPI token [$APP_TOKEN, /etc/app/token, TOKEN in .env, auth.token in app.ini, auth.token in app.json, $(pass show 'app token'), the vault]",
+		fl.String(),
+	)
+
+	t.Run("custom hinter", func(t *testing.T) {
+		defer func() { FlagSourcesHinter = withSourcesHint }()
+
+		FlagSourcesHinter = func(sources []ValueSource, str string) string {
+			return fmt.Sprintf("%s (%d sources)", str, len(sources))
+		}
+		require.Equal(t, "--token value\tAPI token (7 sources)", fl.String())
+	})
+
+	t.Run("fish", func(t *testing.T) {
+		cmd := &Command{
+			Name:  "app",
+			Flags: []Flag{&StringFlag{Name: "token", Usage: "API token", Sources: Files("/etc/app/token")}},
+		}
+
+		res, err := cmd.ToFishCompletion()
+		require.NoError(t, err)
+		require.Contains(t, res, "-l token -r -d 'API token [/etc/app/token]'")
+	})
+
+	t.Run("config file", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := &Command{
+			Name:             "app",
+			Writer:           out,
+			EnableConfigFile: true,
+			ConfigPaths:      []string{"config.json"},
+			FS:               fstest.MapFS{},
+			Commands: []*Command{
+				{
+					Name:  "serve",
+					Flags: []Flag{&IntFlag{Name: "port", Usage: "port to listen on", Sources: EnvVars("APP_PORT")}},
+				},
+			},
+		}
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "serve", "--help"}))
+		require.Contains(t, out.String(), "port to listen on (default: 0) [$APP_PORT, serve.port in config]")
+	})
+}
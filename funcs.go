@@ -29,6 +29,10 @@ type OnUsageErrorFunc func(ctx context.Context, cmd *Command, err error, isSubco
 // InvalidFlagAccessFunc is executed when an invalid flag is accessed from the context.
 type InvalidFlagAccessFunc func(context.Context, *Command, string)
 
+// InvalidArgumentAccessFunc is executed when an undeclared argument is
+// accessed by name from the command.
+type InvalidArgumentAccessFunc func(context.Context, *Command, string)
+
 // ExitErrHandlerFunc is executed if provided in order to handle exitError values
 // returned by Actions and Before/After functions.
 type ExitErrHandlerFunc func(context.Context, *Command, error)
@@ -37,6 +41,10 @@ type ExitErrHandlerFunc func(context.Context, *Command, error)
 // expected to be a single line.
 type FlagStringFunc func(Flag) string
 
+// ArgumentStringFunc is used by the help generation to display an argument,
+// which is expected to be a single line.
+type ArgumentStringFunc func(DocGenerationArgument) string
+
 // FlagNamePrefixFunc is used by the default FlagStringFunc to create prefix
 // text for a flag's full name.
 type FlagNamePrefixFunc func(fullName []string, placeholder string) string
@@ -48,3 +56,7 @@ type FlagEnvHintFunc func(envVars []string, str string) string
 // FlagFileHintFunc is used by the default FlagStringFunc to annotate flag help
 // with the file path details.
 type FlagFileHintFunc func(filePath, str string) string
+
+// FlagSourcesHintFunc is used by the default FlagStringFunc to annotate flag
+// help with the value sources of the flag.
+type FlagSourcesHintFunc func(sources []ValueSource, str string) string
@@ -44,6 +44,14 @@ var HelpPrinterCustom helpPrinterCustom = printHelpCustom
 // VersionPrinter prints the version for the App
 var VersionPrinter = printVersion
 
+// FlagDebugPrinter prints the effective value and origin of every flag
+// applied to the command and its ancestors
+var FlagDebugPrinter = printFlagDebug
+
+// ConfigPrinter prints the effective configuration of the command and its
+// ancestors in one of the formats accepted by PrintConfigFlag
+var ConfigPrinter = printConfig
+
 func buildHelpCommand(withAction bool) *Command {
 	cmd := &Command{
 		Name:      helpName,
@@ -188,7 +196,7 @@ func cliArgContains(flagName string) bool {
 	return false
 }
 
-func printFlagSuggestions(lastArg string, flags []Flag, writer io.Writer) {
+func printFlagSuggestions(lastArg string, flags []Flag, writer io.Writer, foldCase bool) {
 	cur := strings.TrimPrefix(lastArg, "-")
 	cur = strings.TrimPrefix(cur, "-")
 	for _, flag := range flags {
@@ -208,7 +216,7 @@ func printFlagSuggestions(lastArg string, flags []Flag, writer io.Writer) {
 				continue
 			}
 			// match if last argument matches this flag and it is not repeated
-			if strings.HasPrefix(name, cur) && cur != name && !cliArgContains(name) {
+			if hasPrefix(name, cur, foldCase) && cur != name && !cliArgContains(name) {
 				flagCompletion := fmt.Sprintf("%s%s", strings.Repeat("-", count), name)
 				fmt.Fprintln(writer, flagCompletion)
 			}
@@ -231,12 +239,12 @@ func DefaultCompleteWithFlags(cmd *Command) func(ctx context.Context, cmd *Comma
 
 			if strings.HasPrefix(lastArg, "-") {
 				if cmd != nil {
-					printFlagSuggestions(lastArg, cmd.Flags, cmd.Root().Writer)
+					printFlagSuggestions(lastArg, cmd.Flags, cmd.Root().Writer, cmd.Root().CaseInsensitive)
 
 					return
 				}
 
-				printFlagSuggestions(lastArg, cmd.Flags, cmd.Root().Writer)
+				printFlagSuggestions(lastArg, cmd.Flags, cmd.Root().Writer, cmd.Root().CaseInsensitive)
 
 				return
 			}
@@ -328,6 +336,36 @@ func printVersion(cmd *Command) {
 	_, _ = fmt.Fprintf(cmd.Root().Writer, "%v version %v\n", cmd.Name, cmd.Version)
 }
 
+func printFlagDebug(cmd *Command) {
+	w := tabwriter.NewWriter(cmd.Root().Writer, 1, 8, 2, ' ', 0)
+	seen := map[Flag]bool{}
+	lineage := cmd.Lineage()
+
+	for i := len(lineage) - 1; i >= 0; i-- {
+		pCmd := lineage[i]
+
+		for _, fl := range pCmd.appliedFlags {
+			if seen[fl] || isBuiltinFlag(fl) || len(fl.Names()) == 0 {
+				continue
+			}
+			seen[fl] = true
+
+			name := fl.Names()[0]
+			var value any = pCmd.Value(name)
+			if sf, ok := fl.(SensitiveFlag); ok && sf.IsSensitive() {
+				value = maskedValue
+			}
+
+			_, _ = fmt.Fprintf(
+				w, "%[1]s %[2]s%[3]s\t%[4]v\t%[5]s\n",
+				pCmd.FullName(), prefixFor(name), name, value, pCmd.FlagOrigin(name),
+			)
+		}
+	}
+
+	_ = w.Flush()
+}
+
 func handleTemplateError(err error) {
 	if err != nil {
 		tracef("error encountered during template parse: %[1]v", err)
@@ -349,14 +387,15 @@ func printHelpCustom(out io.Writer, templ string, data interface{}, customFuncs
 
 	tracef("building default funcMap")
 	funcMap := template.FuncMap{
-		"join":           strings.Join,
-		"subtract":       subtract,
-		"indent":         indent,
-		"nindent":        nindent,
-		"trim":           strings.TrimSpace,
-		"wrap":           func(input string, offset int) string { return wrap(input, offset, maxLineLength) },
-		"offset":         offset,
-		"offsetCommands": offsetCommands,
+		"join":              strings.Join,
+		"subtract":          subtract,
+		"indent":            indent,
+		"nindent":           nindent,
+		"trim":              strings.TrimSpace,
+		"wrap":              func(input string, offset int) string { return wrap(input, offset, maxLineLength) },
+		"offset":            offset,
+		"offsetCommands":    offsetCommands,
+		"stringifyArgument": ArgumentStringer,
 	}
 
 	if wa, ok := customFuncs["wrapAt"]; ok {
@@ -382,6 +421,10 @@ func printHelpCustom(out io.Writer, templ string, data interface{}, customFuncs
 		handleTemplateError(err)
 	}
 
+	if _, err := t.New("visibleArgumentTemplate").Parse(visibleArgumentTemplate); err != nil {
+		handleTemplateError(err)
+	}
+
 	if _, err := t.New("usageTemplate").Parse(usageTemplate); err != nil {
 		handleTemplateError(err)
 	}
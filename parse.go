@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"flag"
 	"strings"
 )
@@ -8,6 +9,7 @@ import (
 type iterativeParser interface {
 	newFlagSet() (*flag.FlagSet, error)
 	useShortOptionHandling() bool
+	resolveFlagName(name string, long bool) (string, error)
 }
 
 // To enable short-option handling (e.g., "-it" vs "-i -t") we have to
@@ -21,6 +23,17 @@ func parseIter(set *flag.FlagSet, ip iterativeParser, args []string, shellComple
 		tracef("parsing args %[1]q with %[2]T (name=%[3]q)", args, set, set.Name())
 
 		err := set.Parse(args)
+		if err != nil {
+			expanded, nameErr := expandFlagName(ip, args, err)
+			if nameErr != nil && !shellComplete {
+				return nameErr
+			}
+			if expanded != nil {
+				args = expanded
+				continue
+			}
+		}
+
 		if !ip.useShortOptionHandling() || err == nil {
 			if shellComplete {
 				tracef("returning nil due to shellComplete=true")
@@ -79,11 +92,121 @@ func parseIter(set *flag.FlagSet, ip iterativeParser, args []string, shellComple
 	}
 }
 
+// expandFlagName replaces the flag reported as not defined by the parse
+// error with the name of the flag it resolves to, ignoring its case or
+// expanding its prefix. It returns the arguments to parse again starting at
+// that flag, or nil when the flag does not resolve.
+func expandFlagName(ip iterativeParser, args []string, err error) ([]string, error) {
+	trimmed, trimErr := flagFromError(err)
+	if trimErr != nil {
+		return nil, nil
+	}
+
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		dashes := "-"
+		if strings.HasPrefix(arg, "--") {
+			dashes = "--"
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, dashes), "=")
+		if name != trimmed {
+			continue
+		}
+
+		full, err := ip.resolveFlagName(name, dashes == "--")
+		if err != nil || full == "" {
+			return nil, err
+		}
+
+		tracef("expanding flag %[1]q to %[2]q", arg, full)
+
+		expanded := dashes + full
+		if hasValue {
+			expanded += "=" + value
+		}
+
+		// do not include args that parsed correctly so far as it would
+		// trigger Value.Set() on those args again
+		return append([]string{expanded}, args[i+1:]...), nil
+	}
+
+	return nil, nil
+}
+
+// parsePermuted parses flags found anywhere in args up to a "--"
+// terminator, as GNU getopt does. The positional arguments are left as the
+// arguments of the flag set in their original order. When stop returns true
+// for the first positional argument, parsing stops there and leaves it and
+// everything following it untouched, e.g. for a subcommand to parse.
+// During shell completion the last argument is the one being completed, so
+// it is kept as a positional argument for the completion to inspect.
+func parsePermuted(set *flag.FlagSet, ip iterativeParser, args []string, shellComplete bool, stop func(arg string) bool) error {
+	var positional, completing []string
+	if shellComplete && len(args) > 0 {
+		args, completing = args[:len(args)-1], args[len(args)-1:]
+	}
+
+	for {
+		if err := parseIter(set, ip, args, shellComplete); err != nil {
+			return err
+		}
+
+		rest := set.Args()
+		if len(rest) == 0 {
+			break
+		}
+
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" &&
+			(consumed == 1 || !flagTakesValue(set, args[consumed-2])) {
+			tracef("stopping permutation at terminator (rest=%[1]q)", rest)
+
+			positional = append(positional, rest...)
+			break
+		}
+
+		if len(positional) == 0 && stop(rest[0]) {
+			tracef("stopping permutation at %[1]q", rest[0])
+
+			positional = rest
+			break
+		}
+
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	positional = append(positional, completing...)
+
+	tracef("setting positional arguments %[1]q", positional)
+
+	return set.Parse(append([]string{"--"}, positional...))
+}
+
+// flagTakesValue returns whether arg is a flag whose value is given in the
+// following argument
+func flagTakesValue(set *flag.FlagSet, arg string) bool {
+	if !strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+		return false
+	}
+
+	f := set.Lookup(strings.TrimLeft(arg, "-"))
+	return f != nil && !isBoolFlagValue(f.Value)
+}
+
 const providedButNotDefinedErrMsg = "flag provided but not defined: -"
 
 // flagFromError tries to parse a provided flag from an error message. If the
 // parsing fials, it returns the input error and an empty string
 func flagFromError(err error) (string, error) {
+	var unknownErr *UnknownFlagError
+	if errors.As(err, &unknownErr) {
+		return strings.TrimLeft(unknownErr.Flag, "-"), nil
+	}
+
 	errStr := err.Error()
 	trimmed := strings.TrimPrefix(errStr, providedButNotDefinedErrMsg)
 	if errStr == trimmed {
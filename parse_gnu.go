@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"flag"
+	"strings"
+	"unicode/utf8"
+)
+
+// FlagParserMode selects how a root command and all its subcommands parse
+// flags
+type FlagParserMode int
+
+const (
+	// FlagParserCompat parses flags with the flag package of the standard
+	// library. Flags of any length may be given with one or two dashes,
+	// e.g. -verbose, and short options are only combined with
+	// UseShortOptionHandling.
+	FlagParserCompat FlagParserMode = iota
+	// FlagParserGNU parses flags following the POSIX conventions for short
+	// flags and the GNU conventions for long flags. Flags with a single
+	// character name are given with one dash and can be combined, e.g. -vx,
+	// and take their value attached or as the next argument, e.g. -ofile or
+	// -o file. Longer names are given with two dashes and take their value
+	// after an equal sign or as the next argument, e.g. --output=file or
+	// --output file. Errors are returned as *UnknownFlagError,
+	// *MissingFlagValueError, *InvalidFlagValueError or *FlagSyntaxError.
+	FlagParserGNU
+)
+
+func (m FlagParserMode) String() string {
+	if m == FlagParserGNU {
+		return "gnu"
+	}
+	return "compat"
+}
+
+// gnuParser parses flags into a flag set following the FlagParserGNU rules
+type gnuParser struct {
+	set *flag.FlagSet
+	ip  iterativeParser
+}
+
+// parseGNU parses the flags in args into set and leaves the positional
+// arguments as the arguments of the set. Without permute parsing stops at
+// the first positional argument, otherwise it stops at the first positional
+// argument when stop returns true for it, as with parsePermuted. Everything
+// after "--" is positional. During shell completion errors are ignored and
+// the last argument, which is the one being completed, is never parsed.
+func parseGNU(set *flag.FlagSet, ip iterativeParser, args []string, permute, shellComplete bool, stop func(arg string) bool) error {
+	p := &gnuParser{set: set, ip: ip}
+
+	var positional, completing []string
+	if shellComplete && len(args) > 0 {
+		args, completing = args[:len(args)-1], args[len(args)-1:]
+	}
+
+parse:
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		var (
+			consumed int
+			err      error
+		)
+
+		switch {
+		case arg == "--":
+			positional = append(positional, args[i+1:]...)
+			break parse
+
+		case len(arg) < 2 || arg[0] != '-':
+			if !permute || (len(positional) == 0 && stop(arg)) {
+				tracef("stopping flag parsing at %[1]q", arg)
+
+				positional = append(positional, args[i:]...)
+				break parse
+			}
+			positional = append(positional, arg)
+			continue
+
+		case arg[1] == '-':
+			consumed, err = p.parseLong(arg, args[i+1:])
+
+		default:
+			consumed, err = p.parseShort(arg, args[i+1:])
+		}
+
+		if err != nil {
+			if !shellComplete {
+				return err
+			}
+
+			tracef("ignoring %[1]v during shell completion", err)
+
+			positional = append(positional, args[i:]...)
+			break parse
+		}
+
+		i += consumed
+	}
+
+	positional = append(positional, completing...)
+
+	tracef("setting positional arguments %[1]q", positional)
+
+	return set.Parse(append([]string{"--"}, positional...))
+}
+
+// parseLong parses a flag given with two dashes and returns the number of
+// the following arguments it consumed
+func (p *gnuParser) parseLong(arg string, next []string) (int, error) {
+	name, value, hasValue := strings.Cut(arg[2:], "=")
+	if name == "" || name[0] == '-' {
+		return 0, &FlagSyntaxError{Arg: arg}
+	}
+
+	f := p.set.Lookup(name)
+	if f == nil {
+		full, err := p.ip.resolveFlagName(name, true)
+		if err != nil {
+			return 0, err
+		}
+		if full == "" {
+			return 0, &UnknownFlagError{Flag: "--" + name}
+		}
+		name, f = full, p.set.Lookup(full)
+	}
+	if utf8.RuneCountInString(name) == 1 {
+		return 0, &FlagSyntaxError{Arg: arg, Msg: "single-character flags take one dash, use -" + name}
+	}
+
+	if isBoolFlagValue(f.Value) {
+		if !hasValue {
+			value = "true"
+		}
+		return 0, p.setValue("--"+name, name, value)
+	}
+
+	if hasValue {
+		return 0, p.setValue("--"+name, name, value)
+	}
+	if len(next) == 0 {
+		return 0, &MissingFlagValueError{Flag: "--" + name}
+	}
+
+	return 1, p.setValue("--"+name, name, next[0])
+}
+
+// parseShort parses one or more single-character flags given after a
+// single dash and returns the number of the following arguments consumed
+func (p *gnuParser) parseShort(arg string, next []string) (int, error) {
+	for j := 1; j < len(arg); {
+		r, size := utf8.DecodeRuneInString(arg[j:])
+		name := string(r)
+		rest := arg[j+size:]
+
+		f := p.set.Lookup(name)
+		if f == nil {
+			if full, _ := p.ip.resolveFlagName(name, false); full != "" {
+				name, f = full, p.set.Lookup(full)
+			}
+		}
+		if f == nil {
+			if long, _, _ := strings.Cut(arg[1:], "="); utf8.RuneCountInString(long) > 1 && p.set.Lookup(long) != nil {
+				return 0, &FlagSyntaxError{Arg: arg, Msg: "long flags take two dashes, use --" + long}
+			}
+			return 0, &UnknownFlagError{Flag: "-" + name}
+		}
+
+		if isBoolFlagValue(f.Value) {
+			if strings.HasPrefix(rest, "=") {
+				return 0, p.setValue("-"+name, name, rest[1:])
+			}
+			if err := p.setValue("-"+name, name, "true"); err != nil {
+				return 0, err
+			}
+			j += size
+			continue
+		}
+
+		if rest != "" {
+			return 0, p.setValue("-"+name, name, strings.TrimPrefix(rest, "="))
+		}
+		if len(next) == 0 {
+			return 0, &MissingFlagValueError{Flag: "-" + name}
+		}
+
+		return 1, p.setValue("-"+name, name, next[0])
+	}
+
+	return 0, nil
+}
+
+func (p *gnuParser) setValue(flagArg, name, value string) error {
+	tracef("setting flag %[1]q to %[2]q", flagArg, value)
+
+	if err := p.set.Set(name, value); err != nil {
+		return &InvalidFlagValueError{Flag: flagArg, Value: value, Err: err}
+	}
+
+	return nil
+}
+
+func isBoolFlagValue(v flag.Value) bool {
+	b, ok := v.(boolFlag)
+	return ok && b.IsBoolFlag()
+}
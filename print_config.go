@@ -0,0 +1,323 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maskedValue = "*****"
+
+// Formats accepted by PrintConfigFlag
+const (
+	PrintConfigJSON = "json"
+	PrintConfigEnv  = "env"
+	PrintConfigArgs = "args"
+)
+
+// SensitiveFlag is an interface to enable detection of flags whose values
+// must be masked when printed
+type SensitiveFlag interface {
+	// IsSensitive returns true if the flag value must be masked
+	IsSensitive() bool
+}
+
+// configEntry is the effective value of a single flag
+type configEntry struct {
+	cmd   *Command
+	path  []string
+	flag  Flag
+	name  string
+	value any
+}
+
+func lookupPrintConfigFormat(cmd *Command) string {
+	if PrintConfigFlag == nil {
+		return ""
+	}
+
+	for _, pCmd := range cmd.Lineage() {
+		if pCmd.flagSet == nil {
+			continue
+		}
+
+		for _, name := range PrintConfigFlag.Names() {
+			if f := pCmd.flagSet.Lookup(name); f != nil {
+				if v, ok := f.Value.(flag.Getter).Get().(string); ok && v != "" {
+					return v
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// effectiveConfig collects the flags applied to the command and its
+// ancestors, ordered from the root command to the given command
+func effectiveConfig(cmd *Command) []configEntry {
+	entries := []configEntry{}
+	seen := map[Flag]bool{}
+	lineage := cmd.Lineage()
+	path := []string{}
+
+	for i := len(lineage) - 1; i >= 0; i-- {
+		pCmd := lineage[i]
+		if i < len(lineage)-1 {
+			path = append(path, pCmd.Name)
+		}
+
+		for _, fl := range pCmd.appliedFlags {
+			if seen[fl] || isBuiltinFlag(fl) || len(fl.Names()) == 0 {
+				continue
+			}
+			seen[fl] = true
+
+			name := fl.Names()[0]
+			entries = append(entries, configEntry{
+				cmd:   pCmd,
+				path:  append([]string{}, path...),
+				flag:  fl,
+				name:  name,
+				value: pCmd.Value(name),
+			})
+		}
+	}
+
+	return entries
+}
+
+func (e configEntry) isSensitive() bool {
+	sf, ok := e.flag.(SensitiveFlag)
+	return ok && sf.IsSensitive()
+}
+
+// format returns the value in the string form accepted by the flag
+func (e configEntry) format() string {
+	if e.isSensitive() {
+		return maskedValue
+	}
+	return formatConfigValue(e.flag, e.value)
+}
+
+func formatConfigValue(fl Flag, v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		layout := time.RFC3339Nano
+		if tf, ok := fl.(*TimestampFlag); ok && tf.Config.Layout != "" {
+			layout = tf.Config.Layout
+		}
+		return val.Format(layout)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		parts := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			parts = append(parts, formatConfigValue(fl, rv.Index(i).Interface()))
+		}
+		return strings.Join(parts, defaultSliceFlagSeparator)
+	case reflect.Map:
+		parts := make([]string, 0, rv.Len())
+		for _, k := range sortedMapKeys(rv) {
+			parts = append(parts, k+defaultMapFlagKeyValueSeparator+formatConfigValue(fl, rv.MapIndex(reflect.ValueOf(k)).Interface()))
+		}
+		return strings.Join(parts, defaultSliceFlagSeparator)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+// jsonValue returns the value in a form which marshals to JSON readable
+// by the JSON value source
+func (e configEntry) jsonValue() any {
+	if e.isSensitive() {
+		return maskedValue
+	}
+	return jsonConfigValue(e.flag, e.value)
+}
+
+func jsonConfigValue(fl Flag, v any) any {
+	switch v.(type) {
+	case nil, bool, string, int64, uint64, float64:
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		items := make([]any, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, jsonConfigValue(fl, rv.Index(i).Interface()))
+		}
+		return items
+	case reflect.Map:
+		obj := map[string]any{}
+		for _, k := range sortedMapKeys(rv) {
+			obj[k] = jsonConfigValue(fl, rv.MapIndex(reflect.ValueOf(k)).Interface())
+		}
+		return obj
+	}
+
+	return formatConfigValue(fl, v)
+}
+
+func sortedMapKeys(rv reflect.Value) []string {
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, fmt.Sprintf("%v", k.Interface()))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printConfig(cmd *Command, format string) error {
+	w := cmd.Root().Writer
+	entries := effectiveConfig(cmd)
+	profile := cmd.ConfigProfile()
+
+	switch format {
+	case PrintConfigJSON:
+		return printConfigJSON(w, profile, entries)
+	case PrintConfigEnv:
+		printConfigEnv(w, cmd.Root().profileEnvVar(), profile, entries)
+		return nil
+	case PrintConfigArgs:
+		printConfigArgs(w, cmd, profile, entries)
+		return nil
+	}
+
+	return fmt.Errorf(
+		"invalid %[1]s format %[2]q, expected one of %[3]s, %[4]s or %[5]s",
+		PrintConfigFlag.Names()[0], format, PrintConfigJSON, PrintConfigEnv, PrintConfigArgs,
+	)
+}
+
+// printConfigJSON writes an object with the flags of the root command at
+// the top level and those of subcommands nested under the command names.
+// The active profile is written under the "profile" key.
+func printConfigJSON(w io.Writer, profile string, entries []configEntry) error {
+	root := map[string]any{}
+	if profile != "" {
+		root["profile"] = profile
+	}
+
+	for _, e := range entries {
+		obj := root
+		for _, name := range e.path {
+			nested, ok := obj[name].(map[string]any)
+			if !ok {
+				nested = map[string]any{}
+				obj[name] = nested
+			}
+			obj = nested
+		}
+		obj[e.name] = e.jsonValue()
+	}
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// printConfigEnv writes KEY="value" lines in dotenv syntax for every flag
+// bound to an environment variable, preceded by the active profile
+func printConfigEnv(w io.Writer, profileEnvVar, profile string, entries []configEntry) {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`)
+
+	if profile != "" {
+		if profileEnvVar != "" {
+			_, _ = fmt.Fprintf(w, "%[1]s=\"%[2]s\"\n", profileEnvVar, replacer.Replace(profile))
+		} else {
+			_, _ = fmt.Fprintf(w, "# profile %[1]q\n", profile)
+		}
+	}
+
+	for _, e := range entries {
+		df, ok := e.flag.(DocGenerationFlag)
+		if !ok || len(df.GetEnvVars()) == 0 {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(w, "%[1]s=\"%[2]s\"\n", strings.TrimSpace(df.GetEnvVars()[0]), replacer.Replace(e.format()))
+	}
+}
+
+// printConfigArgs writes a command line which reproduces the effective
+// flag values
+func printConfigArgs(w io.Writer, cmd *Command, profile string, entries []configEntry) {
+	args := []string{}
+	lineage := cmd.Lineage()
+
+	for i := len(lineage) - 1; i >= 0; i-- {
+		pCmd := lineage[i]
+		args = append(args, shellQuote(pCmd.Name))
+
+		if profile != "" && pCmd.parent == nil && ProfileFlag != nil {
+			name := ProfileFlag.Names()[0]
+			args = append(args, shellQuote(prefixFor(name)+name+"="+profile))
+		}
+
+		for _, e := range entries {
+			if e.cmd != pCmd {
+				continue
+			}
+
+			flagName := prefixFor(e.name) + e.name
+
+			switch val := e.value.(type) {
+			case bool:
+				if val {
+					args = append(args, flagName)
+				} else {
+					args = append(args, flagName+"=false")
+				}
+				continue
+			}
+
+			rv := reflect.ValueOf(e.value)
+			if !e.isSensitive() && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) {
+				items := []string{}
+				if rv.Kind() == reflect.Slice {
+					for i := 0; i < rv.Len(); i++ {
+						items = append(items, formatConfigValue(e.flag, rv.Index(i).Interface()))
+					}
+				} else {
+					for _, k := range sortedMapKeys(rv) {
+						items = append(items, k+defaultMapFlagKeyValueSeparator+formatConfigValue(e.flag, rv.MapIndex(reflect.ValueOf(k)).Interface()))
+					}
+				}
+				for _, item := range items {
+					args = append(args, shellQuote(flagName+"="+item))
+				}
+				continue
+			}
+
+			args = append(args, shellQuote(flagName+"="+e.format()))
+		}
+	}
+
+	_, _ = fmt.Fprintln(w, strings.Join(args, " "))
+}
+
+// shellQuote quotes s for POSIX shells if it contains special characters
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_=./:,@%+", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
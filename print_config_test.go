@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type printConfigTestValues struct {
+	host     string
+	tags     []string
+	labels   map[string]string
+	verbose  bool
+	password string
+	timeout  time.Duration
+	port     int64
+}
+
+func buildPrintConfigTestCommand(vals *printConfigTestValues, sources func(key, envVar string) ValueSourceChain) *Command {
+	return &Command{
+		Name:              "app",
+		EnablePrintConfig: true,
+		Flags: []Flag{
+			&StringFlag{Name: "host", Sources: sources("host", "APP_HOST"), Destination: &vals.host},
+			&StringSliceFlag{Name: "tag", Sources: sources("tag", "APP_TAG"), Destination: &vals.tags},
+			&StringMapFlag{Name: "label", Sources: sources("label", "APP_LABEL"), Destination: &vals.labels},
+			&BoolFlag{Name: "verbose", Sources: sources("verbose", "APP_VERBOSE"), Destination: &vals.verbose},
+			&StringFlag{Name: "password", Sources: sources("password", "APP_PASSWORD"), Destination: &vals.password, Sensitive: true},
+			&DurationFlag{Name: "timeout", Sources: sources("timeout", "APP_TIMEOUT"), Destination: &vals.timeout},
+		},
+		Commands: []*Command{
+			{
+				Name: "serve",
+				Flags: []Flag{
+					&IntFlag{Name: "port", Sources: sources("serve.port", "APP_SERVE_PORT"), Destination: &vals.port},
+				},
+				Action: func(context.Context, *Command) error { return nil },
+			},
+		},
+	}
+}
+
+func envSources(_, envVar string) ValueSourceChain {
+	return EnvVars(envVar)
+}
+
+func runPrintConfig(t *testing.T, format string) string {
+	out := &bytes.Buffer{}
+	cmd := buildPrintConfigTestCommand(&printConfigTestValues{}, envSources)
+	cmd.Writer = out
+
+	err := cmd.Run(buildTestContext(t), []string{
+		"app", "--host", "it's here", "--tag", "x", "--tag", "y", "--label", "k=v",
+		"--verbose", "--password", "s3cret", "--timeout", "1m30s",
+		"serve", "--port", "8080", "--print-config", format,
+	})
+	require.NoError(t, err)
+
+	return out.String()
+}
+
+func TestCommand_PrintConfig(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		require.JSONEq(t, `{
+			"host": "it's here",
+			"tag": ["x", "y"],
+			"label": {"k": "v"},
+			"verbose": true,
+			"password": "*****",
+			"timeout": "1m30s",
+			"serve": {"port": 8080}
+		}`, runPrintConfig(t, "json"))
+	})
+
+	t.Run("env", func(t *testing.T) {
+		require.Equal(t, `APP_HOST="it's here"
+APP_TAG="x,y"
+APP_LABEL="k=v"
+APP_VERBOSE="true"
+APP_PASSWORD="*****"
+APP_TIMEOUT="1m30s"
+APP_SERVE_PORT="8080"
+`, runPrintConfig(t, "env"))
+	})
+
+	t.Run("args", func(t *testing.T) {
+		require.Equal(t,
+			`app '--host=it'\''s here' --tag=x --tag=y --label=k=v --verbose '--password=*****' --timeout=1m30s serve --port=8080`+"\n",
+			runPrintConfig(t, "args"),
+		)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		cmd := buildPrintConfigTestCommand(&printConfigTestValues{}, envSources)
+		cmd.Writer = &bytes.Buffer{}
+
+		err := cmd.Run(buildTestContext(t), []string{"app", "--print-config", "yaml"})
+		require.EqualError(t, err, `invalid print-config format "yaml", expected one of json, env or args`)
+	})
+
+	t.Run("missing required flag", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := buildPrintConfigTestCommand(&printConfigTestValues{}, envSources)
+		cmd.Writer = out
+		cmd.Flags = append(cmd.Flags, &StringFlag{Name: "token", Required: true})
+		cmd.Commands[0].Flags = append(cmd.Commands[0].Flags, &StringFlag{Name: "cert", Required: true})
+
+		r := require.New(t)
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--print-config", "env"}))
+		r.Contains(out.String(), "APP_HOST=\"\"\n")
+
+		out.Reset()
+		r.NoError(cmd.Run(buildTestContext(t), []string{"app", "--print-config", "env", "serve"}))
+		r.Contains(out.String(), "APP_SERVE_PORT=\"0\"\n")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cmd := buildPrintConfigTestCommand(&printConfigTestValues{}, envSources)
+		cmd.EnablePrintConfig = false
+		cmd.Writer = &bytes.Buffer{}
+		cmd.ErrWriter = &bytes.Buffer{}
+
+		err := cmd.Run(buildTestContext(t), []string{"app", "serve", "--print-config", "json"})
+		require.EqualError(t, err, "flag provided but not defined: -print-config")
+	})
+}
+
+func TestCommand_PrintConfigRoundTrip(t *testing.T) {
+	expected := printConfigTestValues{
+		host:    "it's here",
+		tags:    []string{"x", "y"},
+		labels:  map[string]string{"k": "v"},
+		verbose: true,
+		timeout: 90 * time.Second,
+		port:    8080,
+	}
+
+	dir := t.TempDir()
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(dir, "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(runPrintConfig(t, "json")), 0o600))
+
+		vals := printConfigTestValues{}
+		cmd := buildPrintConfigTestCommand(&vals, func(key, _ string) ValueSourceChain {
+			if key == "password" {
+				return ValueSourceChain{}
+			}
+			return JSONKey(path, key)
+		})
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "serve"}))
+		require.Equal(t, expected, vals)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		path := filepath.Join(dir, ".env")
+		require.NoError(t, os.WriteFile(path, []byte(runPrintConfig(t, "env")), 0o600))
+
+		vals := printConfigTestValues{}
+		cmd := buildPrintConfigTestCommand(&vals, func(_, envVar string) ValueSourceChain {
+			if envVar == "APP_PASSWORD" {
+				return ValueSourceChain{}
+			}
+			return DotEnv(path).EnvVars(envVar)
+		})
+
+		require.NoError(t, cmd.Run(buildTestContext(t), []string{"app", "serve"}))
+		require.Equal(t, expected, vals)
+	})
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// maxResponseFileDepth is the maximum nesting of response files
+const maxResponseFileDepth = 16
+
+// defaultResponseFileEscape is the default prefix of literal @ arguments
+const defaultResponseFileEscape = "@@"
+
+// expandResponseFiles replaces every argument of the form @file before "--"
+// by the arguments read from the file, expanding response files named in
+// it as well. Arguments starting with the ResponseFileEscape are passed on
+// with a single @ in its place.
+func (cmd *Command) expandResponseFiles(args []string, depth int) ([]string, error) {
+	escape := cmd.ResponseFileEscape
+	if escape == "" {
+		escape = defaultResponseFileEscape
+	}
+
+	expanded := make([]string, 0, len(args))
+
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return append(expanded, args[i:]...), nil
+
+		case strings.HasPrefix(arg, escape):
+			expanded = append(expanded, "@"+arg[len(escape):])
+
+		case len(arg) > 1 && arg[0] == '@':
+			fileArgs, err := cmd.readResponseFile(arg[1:], depth)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, fileArgs...)
+
+			// a "--" in the file ends the expansion of the arguments
+			// following it too
+			for _, fileArg := range fileArgs {
+				if fileArg == "--" {
+					return append(expanded, args[i+1:]...), nil
+				}
+			}
+
+		default:
+			expanded = append(expanded, arg)
+		}
+	}
+
+	return expanded, nil
+}
+
+// readResponseFile returns the expanded arguments of the response file at
+// the given path
+func (cmd *Command) readResponseFile(path string, depth int) ([]string, error) {
+	if depth >= maxResponseFileDepth {
+		return nil, fmt.Errorf("response file %[1]q is nested deeper than %[2]d files", path, maxResponseFileDepth)
+	}
+
+	tracef("reading response file %[1]q (cmd=%[2]q)", path, cmd.Name)
+
+	data, err := readSourceFile(cmd.fileSystem(), path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read response file: %[1]w", err)
+	}
+
+	args, err := newArgTokenizer(bytes.NewReader(data)).all()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse response file %[1]q: %[2]w", path, err)
+	}
+
+	return cmd.expandResponseFiles(args, depth+1)
+}
@@ -68,3 +68,19 @@ func suggestCommand(commands []*Command, provided string) (suggestion string) {
 
 	return suggestion
 }
+
+// suggestName takes a list of names and a provided string to suggest the
+// closest name
+func suggestName(candidates []string, provided string) string {
+	distance := 0.0
+	suggestion := ""
+
+	for _, name := range candidates {
+		if newDistance := jaroWinkler(name, provided); newDistance > distance {
+			distance = newDistance
+			suggestion = name
+		}
+	}
+
+	return suggestion
+}
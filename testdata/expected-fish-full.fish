@@ -11,7 +11,7 @@ end
 
 complete -c greet -n '__fish_greet_no_subcommand' -l socket -s s -r -d 'some \'usage\' text'
 complete -c greet -n '__fish_greet_no_subcommand' -f -l flag -s fl -s f -r
-complete -c greet -n '__fish_greet_no_subcommand' -f -l another-flag -s b -d 'another usage text'
+complete -c greet -n '__fish_greet_no_subcommand' -f -l another-flag -s b -d 'another usage text [$EXAMPLE_VARIABLE_NAME]'
 complete -c greet -n '__fish_greet_no_subcommand' -l logfile -r
 complete -c greet -n '__fish_greet_no_subcommand' -f -l help -s h -d 'show help'
 complete -c greet -n '__fish_greet_no_subcommand' -f -l version -s v -d 'print the version'
@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// argTokenizer splits text into arguments following the word splitting of
+// POSIX shells. Arguments are separated by unquoted whitespace and can be
+// quoted with single quotes, which keep every character as is, or double
+// quotes, in which a backslash only escapes $, `, ", \ and newlines. An
+// unquoted backslash escapes the next character, a backslash before a
+// newline continues the line and a # at the start of an argument begins a
+// comment running to the end of the line. Nothing is expanded.
+// It is shared by ReadArgsFromStdin and response files.
+type argTokenizer struct {
+	r *bufio.Reader
+
+	line   int
+	column int
+}
+
+func newArgTokenizer(r io.Reader) *argTokenizer {
+	return &argTokenizer{r: bufio.NewReader(r), line: 1}
+}
+
+// read returns the next rune and keeps track of its position
+func (t *argTokenizer) read() (rune, error) {
+	ch, _, err := t.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	if ch == '\n' {
+		t.line++
+		t.column = 0
+	} else {
+		t.column++
+	}
+
+	return ch, nil
+}
+
+func (t *argTokenizer) errorf(line, column int, msg string) error {
+	return &ArgsSyntaxError{Line: line, Column: column, Msg: msg}
+}
+
+// next returns the next argument, or io.EOF when there are none left. It
+// only reads as far as the end of the argument, so reading can stop at any
+// argument, e.g. at "--" on an interactive stdin.
+func (t *argTokenizer) next() (string, error) {
+	token := strings.Builder{}
+	// whether an argument was started, which may be empty, e.g. ''
+	inWord := false
+
+	for {
+		ch, err := t.read()
+		if err == io.EOF {
+			if inWord {
+				return token.String(), nil
+			}
+			return "", io.EOF
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case ch == '\'':
+			inWord = true
+			if err := t.readSingleQuoted(&token); err != nil {
+				return "", err
+			}
+
+		case ch == '"':
+			inWord = true
+			if err := t.readDoubleQuoted(&token); err != nil {
+				return "", err
+			}
+
+		case ch == '\\':
+			line, column := t.line, t.column
+			escaped, err := t.read()
+			if err == io.EOF {
+				return "", t.errorf(line, column, "unterminated escape")
+			}
+			if err != nil {
+				return "", err
+			}
+			// a backslash before a newline continues the line
+			if escaped != '\n' {
+				inWord = true
+				token.WriteRune(escaped)
+			}
+
+		case ch == '#' && !inWord:
+			if err := t.skipComment(); err != nil {
+				return "", err
+			}
+
+		case unicode.IsSpace(ch):
+			if inWord {
+				return token.String(), nil
+			}
+
+		default:
+			inWord = true
+			token.WriteRune(ch)
+		}
+	}
+}
+
+// readSingleQuoted reads the rest of a single quoted string into token
+func (t *argTokenizer) readSingleQuoted(token *strings.Builder) error {
+	line, column := t.line, t.column
+
+	for {
+		ch, err := t.read()
+		if err == io.EOF {
+			return t.errorf(line, column, "unterminated single quote")
+		}
+		if err != nil {
+			return err
+		}
+		if ch == '\'' {
+			return nil
+		}
+		token.WriteRune(ch)
+	}
+}
+
+// readDoubleQuoted reads the rest of a double quoted string into token
+func (t *argTokenizer) readDoubleQuoted(token *strings.Builder) error {
+	line, column := t.line, t.column
+
+	for {
+		ch, err := t.read()
+		if err == io.EOF {
+			return t.errorf(line, column, "unterminated double quote")
+		}
+		if err != nil {
+			return err
+		}
+
+		switch ch {
+		case '"':
+			return nil
+
+		case '\\':
+			escaped, err := t.read()
+			if err == io.EOF {
+				return t.errorf(line, column, "unterminated double quote")
+			}
+			if err != nil {
+				return err
+			}
+
+			switch escaped {
+			case '$', '`', '"', '\\':
+				token.WriteRune(escaped)
+			case '\n':
+				// line continuation
+			default:
+				token.WriteRune(ch)
+				token.WriteRune(escaped)
+			}
+
+		default:
+			token.WriteRune(ch)
+		}
+	}
+}
+
+// skipComment skips the rest of the line
+func (t *argTokenizer) skipComment() error {
+	for {
+		ch, err := t.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ch == '\n' {
+			return nil
+		}
+	}
+}
+
+// all returns all remaining arguments
+func (t *argTokenizer) all() ([]string, error) {
+	args := []string{}
+
+	for {
+		arg, err := t.next()
+		if err == io.EOF {
+			return args, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+}
@@ -1,9 +1,16 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
 // ValueSource is a source which can be used to look up a value,
@@ -17,6 +24,37 @@ type ValueSource interface {
 	Lookup() (string, bool)
 }
 
+// ContextValueSource is a ValueSource which can report errors and be
+// cancelled, such as a source reading a file or running a command.
+// FlagBase.Apply prefers LookupContext over Lookup when available.
+type ContextValueSource interface {
+	ValueSource
+
+	// LookupContext returns the value from the source and if it was found,
+	// or an error if the source failed to look up the value
+	LookupContext(ctx context.Context) (string, bool, error)
+}
+
+// DescribableValueSource is a ValueSource which can describe itself in
+// help and documentation output
+type DescribableValueSource interface {
+	ValueSource
+
+	// Describe returns a short description of the source, such as
+	// $APP_TOKEN for an environment variable or the path of a file
+	Describe() string
+}
+
+// describeValueSource returns the description of the source, falling back
+// to its String representation
+func describeValueSource(src ValueSource) string {
+	if ds, ok := src.(DescribableValueSource); ok {
+		return ds.Describe()
+	}
+
+	return src.String()
+}
+
 // ValueSourceChain contains an ordered series of ValueSource that
 // allows for lookup where the first ValueSource to resolve is
 // returned
@@ -59,13 +97,92 @@ func (vsc *ValueSourceChain) LookupWithSource() (string, ValueSource, bool) {
 	return "", nil, false
 }
 
+// LookupWithSourceContext is like LookupWithSource, but prefers the
+// LookupContext method of sources implementing ContextValueSource. It stops
+// at the first error, returning it along with the failing source.
+func (vsc *ValueSourceChain) LookupWithSourceContext(ctx context.Context) (string, ValueSource, bool, error) {
+	for _, src := range vsc.Chain {
+		value, found, err := lookupSourceContext(ctx, src)
+		if err != nil {
+			return "", src, false, err
+		}
+		if found {
+			return value, src, true, nil
+		}
+	}
+
+	return "", nil, false, nil
+}
+
+// lookupAllWithSourceContext looks up every source of the chain in order
+// and calls fn with the value of each source which resolves. It stops at
+// the first error, returning it along with the failing source.
+func (vsc *ValueSourceChain) lookupAllWithSourceContext(
+	ctx context.Context,
+	fn func(value string, src ValueSource) error,
+) (ValueSource, error) {
+	for _, src := range vsc.Chain {
+		value, found, err := lookupSourceContext(ctx, src)
+		if err != nil {
+			return src, err
+		}
+		if !found {
+			continue
+		}
+		if err := fn(value, src); err != nil {
+			return src, err
+		}
+	}
+
+	return nil, nil
+}
+
+// lookupSourceContext looks up the given source, preferring LookupContext
+// when it implements ContextValueSource
+func lookupSourceContext(ctx context.Context, src ValueSource) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+
+	if cs, ok := src.(ContextValueSource); ok {
+		return cs.LookupContext(ctx)
+	}
+
+	value, found := src.Lookup()
+	return value, found, nil
+}
+
 // envVarValueSource encapsulates a ValueSource from an environment variable
 type envVarValueSource struct {
 	Key string
+
+	lookupEnv func(key string) (string, bool) // environment the variable is described for, the process environment if nil
 }
 
 func (e *envVarValueSource) Lookup() (string, bool) {
-	return os.LookupEnv(strings.TrimSpace(string(e.Key)))
+	v, ok, _ := e.LookupContext(context.Background())
+	return v, ok
+}
+
+// LookupContext looks up the variable in the Env of the command running in
+// the given context, or in the process environment
+func (e *envVarValueSource) LookupContext(ctx context.Context) (string, bool, error) {
+	v, ok := lookupEnvContext(ctx, strings.TrimSpace(e.Key))
+	return v, ok, nil
+}
+
+// Describe returns the variable as referenced in the shell, e.g. $APP_TOKEN
+func (e *envVarValueSource) Describe() string {
+	lookupEnv := e.lookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	if isWindowsCmd(lookupEnv) {
+		return "%" + e.Key + "%"
+	}
+
+	return "$" + e.Key
 }
 
 func (e *envVarValueSource) String() string { return fmt.Sprintf("environment variable %[1]q", e.Key) }
@@ -91,10 +208,37 @@ type fileValueSource struct {
 }
 
 func (f *fileValueSource) Lookup() (string, bool) {
-	data, err := os.ReadFile(f.Path)
-	return string(data), err == nil
+	v, ok, err := f.LookupContext(context.Background())
+	return v, ok && err == nil
 }
 
+// LookupContext returns the contents of the file, or the error encountered
+// reading it. A missing file is reported as not found. The file is read
+// from the FS of the command running in the given context if it sets one.
+func (f *fileValueSource) LookupContext(ctx context.Context) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+
+	var fsys fs.FS
+	if cmd := commandFromContext(ctx); cmd != nil {
+		fsys = cmd.fileSystem()
+	}
+
+	data, err := readSourceFile(fsys, f.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	return string(data), true, nil
+}
+
+// Describe returns the path of the file
+func (f *fileValueSource) Describe() string { return f.Path }
+
 func (f *fileValueSource) String() string { return fmt.Sprintf("file %[1]q", f.Path) }
 func (f *fileValueSource) GoString() string {
 	return fmt.Sprintf("&fileValueSource{Path:%[1]q}", f.Path)
@@ -111,3 +255,138 @@ func Files(paths ...string) ValueSourceChain {
 
 	return vsc
 }
+
+// SourceParseError is returned when a file backing a ValueSource
+// cannot be parsed
+type SourceParseError struct {
+	Path string // path of the malformed file
+	Line int    // 1-based line number of the offending line
+	Msg  string // description of the problem
+}
+
+func (e *SourceParseError) Error() string {
+	return fmt.Sprintf("%[1]s:%[2]d: %[3]s", e.Path, e.Line, e.Msg)
+}
+
+// parsedFile is a cached parse result of a file backing a ValueSource
+type parsedFile struct {
+	modTime time.Time
+	size    int64
+	values  map[string]string
+	err     error
+}
+
+// fileCache caches parsed files by path, re-parsing a file only when
+// its modification time or size changes
+type fileCache struct {
+	mu    sync.Mutex
+	files map[string]*parsedFile
+}
+
+// lookupFileKey looks up a key in a file parsed through the given cache
+func lookupFileKey(
+	ctx context.Context,
+	cache *fileCache,
+	path, key string,
+	parse func(path string, data []byte) (map[string]string, error),
+) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+
+	var fsys fs.FS
+	if cmd := commandFromContext(ctx); cmd != nil {
+		fsys = cmd.fileSystem()
+		cache = cmd.sourceCache(cache)
+	}
+
+	values, err := cache.load(fsys, path, parse)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	v, ok := values[key]
+	return v, ok, nil
+}
+
+// forget drops the cached parse result of the given file
+func (fc *fileCache) forget(path string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	delete(fc.files, path)
+}
+
+// load returns the parsed contents of the file at path in fsys, or in the
+// operating system's file system when fsys is nil
+func (fc *fileCache) load(
+	fsys fs.FS,
+	path string,
+	parse func(path string, data []byte) (map[string]string, error),
+) (map[string]string, error) {
+	info, err := statSourceFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if pf, ok := fc.files[path]; ok && pf.modTime.Equal(info.ModTime()) && pf.size == info.Size() {
+		return pf.values, pf.err
+	}
+
+	data, err := readSourceFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	tracef("parsing source file %[1]q", path)
+
+	values, err := parse(path, data)
+	if fc.files == nil {
+		fc.files = map[string]*parsedFile{}
+	}
+	fc.files[path] = &parsedFile{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		values:  values,
+		err:     err,
+	}
+
+	return values, err
+}
+
+// readSourceFile reads the file at path from fsys, or from the operating
+// system's file system when fsys is nil
+func readSourceFile(fsys fs.FS, path string) ([]byte, error) {
+	if fsys == nil {
+		return os.ReadFile(path)
+	}
+
+	return fs.ReadFile(fsys, fsPath(path))
+}
+
+// statSourceFile describes the file at path in fsys, or in the operating
+// system's file system when fsys is nil
+func statSourceFile(fsys fs.FS, path string) (fs.FileInfo, error) {
+	if fsys == nil {
+		return os.Stat(path)
+	}
+
+	return fs.Stat(fsys, fsPath(path))
+}
+
+// fsPath converts an operating system path into a path valid for fs.FS,
+// which is unrooted and slash separated
+func fsPath(name string) string {
+	name = strings.TrimLeft(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
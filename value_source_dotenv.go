@@ -0,0 +1,363 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// dotEnvCache caches parsed dotenv files across lookups
+var dotEnvCache = &fileCache{}
+
+// DotEnvFiles is an ordered set of dotenv files which can be used to
+// look up environment variable style keys, typically for use with a
+// cli.Flag
+type DotEnvFiles struct {
+	Paths []string
+}
+
+// DotEnv creates DotEnvFiles from the given paths. When a key is
+// defined in more than one file the earlier path takes precedence.
+//
+// Files use the common dotenv syntax: KEY=value assignments with an
+// optional "export" prefix, '#' comments, single-quoted literal values,
+// double-quoted values with escapes and ${VAR} interpolation, and
+// unquoted values with ${VAR} interpolation.
+func DotEnv(paths ...string) *DotEnvFiles {
+	return &DotEnvFiles{Paths: paths}
+}
+
+// EnvVars is a helper function to encapsulate a number of
+// dotEnvValueSource together as a ValueSourceChain. Every key
+// is looked up in every file before moving on to the next key.
+func (d *DotEnvFiles) EnvVars(keys ...string) ValueSourceChain {
+	vsc := ValueSourceChain{Chain: []ValueSource{}}
+
+	for _, key := range keys {
+		for _, path := range d.Paths {
+			vsc.Chain = append(vsc.Chain, &dotEnvValueSource{Path: path, Key: key})
+		}
+	}
+
+	return vsc
+}
+
+// Load parses all files and returns their merged values. Missing files
+// are skipped while malformed files are reported as a *SourceParseError.
+func (d *DotEnvFiles) Load() (map[string]string, error) {
+	merged := map[string]string{}
+
+	for i := len(d.Paths) - 1; i >= 0; i-- {
+		values, err := dotEnvCache.load(nil, d.Paths[i], parseDotEnv)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range values {
+			merged[k] = v
+		}
+	}
+
+	return merged, nil
+}
+
+// dotEnvValueSource encapsulates a ValueSource from a key in a dotenv file
+type dotEnvValueSource struct {
+	Path string
+	Key  string
+}
+
+func (d *dotEnvValueSource) Lookup() (string, bool) {
+	v, ok, _ := d.LookupContext(context.Background())
+	return v, ok
+}
+
+// LookupContext returns the value of the key, or the error encountered
+// reading or parsing the file. A missing file is reported as not found.
+func (d *dotEnvValueSource) LookupContext(ctx context.Context) (string, bool, error) {
+	parse := func(path string, data []byte) (map[string]string, error) {
+		return parseDotEnvEnv(path, data, func(key string) (string, bool) {
+			return lookupEnvContext(ctx, key)
+		})
+	}
+
+	return lookupFileKey(ctx, dotEnvCache, d.Path, d.Key, parse)
+}
+
+// Describe returns the key and the path of the file, e.g. DB_HOST in .env
+func (d *dotEnvValueSource) Describe() string {
+	return d.Key + " in " + d.Path
+}
+
+func (d *dotEnvValueSource) String() string {
+	return fmt.Sprintf("key %[1]q in dotenv file %[2]q", d.Key, d.Path)
+}
+
+func (d *dotEnvValueSource) GoString() string {
+	return fmt.Sprintf("&dotEnvValueSource{Path:%[1]q,Key:%[2]q}", d.Path, d.Key)
+}
+
+type dotEnvParser struct {
+	path      string
+	lines     []string
+	values    map[string]string
+	lookupEnv func(key string) (string, bool)
+}
+
+func parseDotEnv(path string, data []byte) (map[string]string, error) {
+	return parseDotEnvEnv(path, data, os.LookupEnv)
+}
+
+// parseDotEnvEnv parses a dotenv file, interpolating variables not defined
+// in the file itself from lookupEnv
+func parseDotEnvEnv(path string, data []byte, lookupEnv func(key string) (string, bool)) (map[string]string, error) {
+	p := &dotEnvParser{
+		path:      path,
+		lines:     strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"),
+		values:    map[string]string{},
+		lookupEnv: lookupEnv,
+	}
+
+	for i := 0; i < len(p.lines); i++ {
+		end, err := p.parseAssignment(i)
+		if err != nil {
+			return nil, err
+		}
+		i = end
+	}
+
+	return p.values, nil
+}
+
+// parseAssignment parses the assignment starting at line index i and
+// returns the index of the last line it consumed
+func (p *dotEnvParser) parseAssignment(i int) (int, error) {
+	line := strings.TrimSpace(p.lines[i])
+	if line == "" || line[0] == '#' {
+		return i, nil
+	}
+
+	if strings.HasPrefix(line, "export") && len(line) > 6 && (line[6] == ' ' || line[6] == '\t') {
+		line = strings.TrimSpace(line[6:])
+	}
+
+	key, rest, ok := strings.Cut(line, "=")
+	if !ok {
+		return i, p.errorf(i, "expected KEY=value, got %[1]q", line)
+	}
+
+	key = strings.TrimSpace(key)
+	if !isDotEnvKey(key) {
+		return i, p.errorf(i, "invalid key %[1]q", key)
+	}
+
+	rest = strings.TrimLeft(rest, " \t")
+
+	var (
+		value string
+		end   = i
+		err   error
+	)
+
+	switch {
+	case strings.HasPrefix(rest, "'"):
+		value, end, err = p.parseSingleQuoted(i, rest[1:])
+	case strings.HasPrefix(rest, `"`):
+		value, end, err = p.parseDoubleQuoted(i, rest[1:])
+	default:
+		value, err = p.expand(i, stripInlineComment(rest, "#"))
+	}
+
+	if err != nil {
+		return end, err
+	}
+
+	p.values[key] = value
+	return end, nil
+}
+
+func (p *dotEnvParser) parseSingleQuoted(i int, s string) (string, int, error) {
+	start := i
+	sb := strings.Builder{}
+
+	for {
+		if idx := strings.IndexByte(s, '\''); idx >= 0 {
+			sb.WriteString(s[:idx])
+			return sb.String(), i, p.checkTrailing(i, s[idx+1:])
+		}
+
+		sb.WriteString(s)
+
+		i++
+		if i >= len(p.lines) {
+			return "", start, p.errorf(start, "unterminated single-quoted value")
+		}
+
+		sb.WriteByte('\n')
+		s = p.lines[i]
+	}
+}
+
+func (p *dotEnvParser) parseDoubleQuoted(i int, s string) (string, int, error) {
+	start := i
+	sb := strings.Builder{}
+
+	for {
+		for j := 0; j < len(s); j++ {
+			switch c := s[j]; c {
+			case '\\':
+				if j+1 == len(s) {
+					sb.WriteByte(c)
+					continue
+				}
+				j++
+				switch s[j] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 'r':
+					sb.WriteByte('\r')
+				case 't':
+					sb.WriteByte('\t')
+				case '"', '\\', '$':
+					sb.WriteByte(s[j])
+				default:
+					sb.WriteByte(c)
+					sb.WriteByte(s[j])
+				}
+			case '"':
+				return sb.String(), i, p.checkTrailing(i, s[j+1:])
+			case '$':
+				val, n, err := p.lookupVar(i, s[j:])
+				if err != nil {
+					return "", i, err
+				}
+				sb.WriteString(val)
+				j += n - 1
+			default:
+				sb.WriteByte(c)
+			}
+		}
+
+		i++
+		if i >= len(p.lines) {
+			return "", start, p.errorf(start, "unterminated double-quoted value")
+		}
+
+		sb.WriteByte('\n')
+		s = p.lines[i]
+	}
+}
+
+// expand replaces variable references in an unquoted value
+func (p *dotEnvParser) expand(i int, s string) (string, error) {
+	sb := strings.Builder{}
+
+	for j := 0; j < len(s); j++ {
+		if s[j] != '$' {
+			sb.WriteByte(s[j])
+			continue
+		}
+
+		val, n, err := p.lookupVar(i, s[j:])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(val)
+		j += n - 1
+	}
+
+	return sb.String(), nil
+}
+
+// lookupVar resolves the ${VAR} or $VAR reference at the start of s and
+// returns its value along with the number of bytes consumed. Variables
+// defined earlier in the file take precedence over the environment.
+func (p *dotEnvParser) lookupVar(i int, s string) (string, int, error) {
+	var name string
+	n := 0
+
+	if strings.HasPrefix(s, "${") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", 0, p.errorf(i, "unterminated variable reference %[1]q", s)
+		}
+		name = s[2:end]
+		if !isDotEnvKey(name) {
+			return "", 0, p.errorf(i, "invalid variable reference %[1]q", s[:end+1])
+		}
+		n = end + 1
+	} else {
+		n = 1
+		for n < len(s) && s[n] != '.' && isDotEnvKeyChar(s[n], n == 1) {
+			n++
+		}
+		if n == 1 {
+			return "$", 1, nil
+		}
+		name = s[1:n]
+	}
+
+	if v, ok := p.values[name]; ok {
+		return v, n, nil
+	}
+
+	v, _ := p.lookupEnv(name)
+	return v, n, nil
+}
+
+func (p *dotEnvParser) checkTrailing(i int, s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" || s[0] == '#' {
+		return nil
+	}
+	return p.errorf(i, "unexpected %[1]q after quoted value", s)
+}
+
+func (p *dotEnvParser) errorf(i int, format string, a ...any) error {
+	return &SourceParseError{
+		Path: p.path,
+		Line: i + 1,
+		Msg:  fmt.Sprintf(format, a...),
+	}
+}
+
+func isDotEnvKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if !isDotEnvKeyChar(key[i], i == 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDotEnvKeyChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9', c == '.':
+		return !first
+	}
+	return false
+}
+
+// stripInlineComment removes a trailing comment from an unquoted value.
+// A comment starts with any of the given characters when it is at the
+// beginning of the value or preceded by whitespace.
+func stripInlineComment(s, commentChars string) string {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(commentChars, s[i]) >= 0 && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return strings.TrimSpace(s)
+}
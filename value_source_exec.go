@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// deferredValueSource is implemented by sources which are only looked up
+// once the command line is parsed and only when the flag was not set on it
+type deferredValueSource interface {
+	ValueSource
+
+	deferLookup()
+}
+
+// deferredSourceFlag is implemented by flags which hold deferred sources
+type deferredSourceFlag interface {
+	resolveDeferredSources(ctx context.Context, set *flag.FlagSet) error
+}
+
+// splitDeferredSources splits the chain before its first deferred source.
+// The sources following it are deferred too to keep their precedence.
+func splitDeferredSources(vsc ValueSourceChain) (ValueSourceChain, *ValueSourceChain) {
+	for i, vs := range vsc.Chain {
+		if _, ok := vs.(deferredValueSource); ok {
+			return ValueSourceChain{Chain: vsc.Chain[:i]}, &ValueSourceChain{Chain: vsc.Chain[i:]}
+		}
+	}
+
+	return vsc, nil
+}
+
+// resolveDeferredSources looks up the deferred sources of the flags applied
+// to the command which were not set on the command line, except those of
+// persistent flags if skipPersistent is set
+func (cmd *Command) resolveDeferredSources(ctx context.Context, skipPersistent bool) error {
+	for _, fl := range cmd.appliedFlags {
+		if pfl, ok := fl.(PersistentFlag); ok && pfl.IsPersistent() && skipPersistent {
+			continue
+		}
+		if df, ok := fl.(deferredSourceFlag); ok {
+			if err := df.resolveDeferredSources(ctx, cmd.flagSet); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// execOutput is the cached result of running a command
+type execOutput struct {
+	value string
+	err   error
+}
+
+// ExecOutput is a helper function to encapsulate the output of a command
+// as a ValueSourceChain, such as a password manager or credential helper:
+//
+//	Sources: cli.ExecOutput("pass", "show", "api-token")
+//
+// The command is run without a shell, only when the flag was not set on
+// the command line and no earlier source provided a value. It is cancelled
+// when the context of the command is done, so a timeout can be set with
+// context.WithTimeout. Trailing newlines are trimmed from its output,
+// which is cached for the rest of the run of the root command. A command
+// exiting with an error is reported as a *SourceLookupError.
+func ExecOutput(argv ...string) ValueSourceChain {
+	return ValueSourceChain{Chain: []ValueSource{&execValueSource{Args: argv}}}
+}
+
+// execValueSource encapsulates a ValueSource from the output of a command
+type execValueSource struct {
+	Args []string
+}
+
+func (e *execValueSource) deferLookup() {}
+
+func (e *execValueSource) Lookup() (string, bool) {
+	v, ok, _ := e.LookupContext(context.Background())
+	return v, ok
+}
+
+// LookupContext runs the command in the environment of the command running
+// in the given context, or returns its cached output when it was already
+// run by the root command
+func (e *execValueSource) LookupContext(ctx context.Context) (string, bool, error) {
+	if len(e.Args) == 0 {
+		return "", false, errors.New("no command to run")
+	}
+
+	var (
+		cache map[string]execOutput
+		env   []string
+	)
+	if cmd := commandFromContext(ctx); cmd != nil {
+		env = cmd.environ()
+
+		root := cmd.Root()
+		if root.execOutputs == nil {
+			root.execOutputs = map[string]execOutput{}
+		}
+		cache = root.execOutputs
+	}
+
+	key := strings.Join(e.Args, "\x00")
+	if out, ok := cache[key]; ok {
+		return out.value, out.err == nil, out.err
+	}
+
+	tracef("running command %[1]q for value source", e.Args)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	c := exec.CommandContext(ctx, e.Args[0], e.Args[1:]...)
+	c.Env = env
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	out := execOutput{}
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%[1]w: %[2]s", err, msg)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("%[1]w: %[2]w", ctxErr, err)
+		}
+		out.err = err
+	} else {
+		out.value = strings.TrimRight(stdout.String(), "\r\n")
+	}
+
+	if cache != nil {
+		cache[key] = out
+	}
+
+	return out.value, out.err == nil, out.err
+}
+
+// command returns the shell-quoted command line
+func (e *execValueSource) command() string {
+	args := make([]string, 0, len(e.Args))
+	for _, arg := range e.Args {
+		args = append(args, shellQuote(arg))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// Describe returns the command in shell syntax, never its output
+func (e *execValueSource) Describe() string {
+	return "$(" + e.command() + ")"
+}
+
+func (e *execValueSource) String() string {
+	return fmt.Sprintf("output of command %[1]q", e.command())
+}
+
+func (e *execValueSource) GoString() string {
+	return fmt.Sprintf("&execValueSource{Args:%[1]q}", e.Args)
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// iniCache caches parsed INI files across lookups
+var iniCache = &fileCache{}
+
+// iniValueSource encapsulates a ValueSource from a key in an INI file
+type iniValueSource struct {
+	Path string
+	Key  string
+}
+
+func (i *iniValueSource) Lookup() (string, bool) {
+	v, ok, _ := i.LookupContext(context.Background())
+	return v, ok
+}
+
+// LookupContext returns the value of the key, or the error encountered
+// reading or parsing the file. A missing file is reported as not found.
+func (i *iniValueSource) LookupContext(ctx context.Context) (string, bool, error) {
+	return lookupFileKey(ctx, iniCache, i.Path, i.Key, parseINI)
+}
+
+// Describe returns the key and the path of the file, e.g. db.host in app.ini
+func (i *iniValueSource) Describe() string {
+	return i.Key + " in " + i.Path
+}
+
+func (i *iniValueSource) String() string {
+	return fmt.Sprintf("key %[1]q in ini file %[2]q", i.Key, i.Path)
+}
+
+func (i *iniValueSource) GoString() string {
+	return fmt.Sprintf("&iniValueSource{Path:%[1]q,Key:%[2]q}", i.Path, i.Key)
+}
+
+// INIKey is a helper function to encapsulate an iniValueSource as a
+// ValueSourceChain. The key is written as "section.key", or as just
+// "key" for entries which appear before the first section header.
+//
+// Lines starting with ';' or '#' are comments, values may be written
+// as "key = value" or "key: value" and may be quoted.
+func INIKey(path, key string) ValueSourceChain {
+	return ValueSourceChain{Chain: []ValueSource{&iniValueSource{Path: path, Key: key}}}
+}
+
+func parseINI(path string, data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	section := ""
+
+	errorf := func(i int, format string, a ...any) error {
+		return &SourceParseError{
+			Path: path,
+			Line: i + 1,
+			Msg:  fmt.Sprintf(format, a...),
+		}
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, errorf(i, "unterminated section header %[1]q", line)
+			}
+			if rest := stripInlineComment(line[end+1:], ";#"); rest != "" {
+				return nil, errorf(i, "unexpected %[1]q after section header", rest)
+			}
+			section = strings.TrimSpace(line[1:end])
+			if section == "" {
+				return nil, errorf(i, "empty section name")
+			}
+			continue
+		}
+
+		idx := strings.IndexAny(line, "=:")
+		if idx < 0 {
+			return nil, errorf(i, "expected \"key = value\", got %[1]q", line)
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		if key == "" {
+			return nil, errorf(i, "missing key in %[1]q", line)
+		}
+
+		value := strings.TrimSpace(line[idx+1:])
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			end := strings.IndexByte(value[1:], value[0])
+			if end < 0 {
+				return nil, errorf(i, "unterminated quoted value for key %[1]q", key)
+			}
+			if rest := stripInlineComment(value[end+2:], ";#"); rest != "" {
+				return nil, errorf(i, "unexpected %[1]q after quoted value", rest)
+			}
+			value = value[1 : end+1]
+		} else {
+			value = stripInlineComment(value, ";#")
+		}
+
+		if section != "" {
+			key = section + "." + key
+		}
+
+		values[key] = value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonCache caches parsed JSON files across lookups
+var jsonCache = &fileCache{}
+
+// jsonValueSource encapsulates a ValueSource from a key in a JSON file
+type jsonValueSource struct {
+	Path string
+	Key  string
+}
+
+func (j *jsonValueSource) Lookup() (string, bool) {
+	v, ok, _ := j.LookupContext(context.Background())
+	return v, ok
+}
+
+// LookupContext returns the value of the key, or the error encountered
+// reading or parsing the file. A missing file is reported as not found.
+func (j *jsonValueSource) LookupContext(ctx context.Context) (string, bool, error) {
+	return lookupFileKey(ctx, jsonCache, j.Path, j.Key, parseJSON)
+}
+
+// Describe returns the key and the path of the file, e.g. db.host in app.json
+func (j *jsonValueSource) Describe() string {
+	return j.Key + " in " + j.Path
+}
+
+func (j *jsonValueSource) String() string {
+	return fmt.Sprintf("key %[1]q in json file %[2]q", j.Key, j.Path)
+}
+
+func (j *jsonValueSource) GoString() string {
+	return fmt.Sprintf("&jsonValueSource{Path:%[1]q,Key:%[2]q}", j.Path, j.Key)
+}
+
+// JSONKey is a helper function to encapsulate a jsonValueSource as a
+// ValueSourceChain. Nested objects are addressed with dotted keys such
+// as "server.port". Arrays are joined with the slice flag separator and
+// objects are written as key=value pairs, so that they can be used with
+// slice and map flags.
+func JSONKey(path, key string) ValueSourceChain {
+	return ValueSourceChain{Chain: []ValueSource{&jsonValueSource{Path: path, Key: key}}}
+}
+
+// parseJSON parses a JSON object into a flat map of dotted keys
+func parseJSON(path string, data []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var root map[string]any
+	if err := dec.Decode(&root); err != nil {
+		return nil, jsonParseError(path, data, dec, err)
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, jsonParseError(path, data, dec, errors.New("unexpected data after top-level object"))
+	}
+
+	values := map[string]string{}
+	flattenJSON(values, "", root)
+
+	return values, nil
+}
+
+func flattenJSON(values map[string]string, prefix string, obj map[string]any) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		if s, ok := flattenConfigValue(v); ok {
+			values[key] = s
+		}
+
+		if nested, ok := v.(map[string]any); ok {
+			flattenJSON(values, key, nested)
+		}
+	}
+}
+
+// flattenConfigValue converts a decoded configuration value into the
+// string form accepted by flag values. Null values are reported as unset.
+func flattenConfigValue(v any) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return val, true
+	case []any:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			s, _ := flattenConfigValue(item)
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, defaultSliceFlagSeparator), true
+	case map[string]any:
+		parts := make([]string, 0, len(val))
+		for _, k := range sortedKeys(val) {
+			s, _ := flattenConfigValue(val[k])
+			parts = append(parts, k+defaultMapFlagKeyValueSeparator+s)
+		}
+		return strings.Join(parts, defaultSliceFlagSeparator), true
+	default:
+		return fmt.Sprintf("%v", val), true
+	}
+}
+
+func jsonParseError(path string, data []byte, dec *json.Decoder, err error) error {
+	offset := dec.InputOffset()
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		offset = int64(len(data))
+	}
+
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return &SourceParseError{
+		Path: path,
+		Line: bytes.Count(data[:offset], []byte("\n")) + 1,
+		Msg:  err.Error(),
+	}
+}
@@ -1,11 +1,15 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -90,6 +94,28 @@ func TestFileValueSource(t *testing.T) {
 		})
 	})
 
+	t.Run("implements ContextValueSource", func(t *testing.T) {
+		r := require.New(t)
+		dir := t.TempDir()
+
+		r.Implements((*ContextValueSource)(nil), &fileValueSource{})
+
+		_, ok, err := (&fileValueSource{Path: filepath.Join(dir, "missing")}).LookupContext(context.Background())
+		r.NoError(err)
+		r.False(ok)
+
+		_, ok, err = (&fileValueSource{Path: dir}).LookupContext(context.Background())
+		r.ErrorContains(err, "is a directory")
+		r.False(ok)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, ok, err = (&fileValueSource{Path: "/dev/null"}).LookupContext(ctx)
+		r.ErrorIs(err, context.Canceled)
+		r.False(ok)
+	})
+
 	t.Run("implements fmt.Stringer", func(t *testing.T) {
 		src := &fileValueSource{Path: "/dev/null"}
 		r := require.New(t)
@@ -163,6 +189,58 @@ func TestValueSourceChain(t *testing.T) {
 	})
 }
 
+func TestValueSourceChain_LookupWithSourceContext(t *testing.T) {
+	r := require.New(t)
+	failing := &errorValueSource{err: errors.New("permission denied")}
+
+	vsc := &ValueSourceChain{Chain: []ValueSource{
+		&contextValueSource{},
+		&staticValueSource{v: "soup"},
+		failing,
+	}}
+	str, src, ok, err := vsc.LookupWithSourceContext(context.Background())
+	r.NoError(err)
+	r.True(ok)
+	r.Equal("soup", str)
+	r.Equal("soup", src.String())
+
+	vsc = &ValueSourceChain{Chain: []ValueSource{failing, &staticValueSource{v: "soup"}}}
+	_, src, ok, err = vsc.LookupWithSourceContext(context.Background())
+	r.EqualError(err, "permission denied")
+	r.Equal(failing, src)
+	r.False(ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	vsc = &ValueSourceChain{Chain: []ValueSource{&staticValueSource{v: "soup"}}}
+	_, _, ok, err = vsc.LookupWithSourceContext(ctx)
+	r.ErrorIs(err, context.Canceled)
+	r.False(ok)
+}
+
+// contextValueSource is a ContextValueSource which never finds a value
+type contextValueSource struct{}
+
+func (*contextValueSource) GoString() string       { return "&contextValueSource{}" }
+func (*contextValueSource) String() string         { return "context source" }
+func (*contextValueSource) Lookup() (string, bool) { return "", false }
+func (*contextValueSource) LookupContext(context.Context) (string, bool, error) {
+	return "", false, nil
+}
+
+// errorValueSource is a ContextValueSource which always fails
+type errorValueSource struct {
+	err error
+}
+
+func (*errorValueSource) GoString() string       { return "&errorValueSource{}" }
+func (*errorValueSource) String() string         { return "failing source" }
+func (*errorValueSource) Lookup() (string, bool) { return "", false }
+func (e *errorValueSource) LookupContext(context.Context) (string, bool, error) {
+	return "", false, e.err
+}
+
 type staticValueSource struct {
 	v string
 }
@@ -172,3 +250,434 @@ func (svs *staticValueSource) GoString() string {
 }
 func (svs *staticValueSource) String() string         { return svs.v }
 func (svs *staticValueSource) Lookup() (string, bool) { return svs.v, true }
+
+func TestDotEnvParse(t *testing.T) {
+	t.Setenv("URFAVE_CLI_TEST_HOME", "/home/test")
+
+	values, err := parseDotEnv(".env", []byte(`# comment
+PLAIN=value
+SPACED = spaced value  # trailing comment
+HASH=a#b
+export EXPORTED=yes
+SINGLE='literal ${PLAIN} \n'
+DOUBLE="escaped \"quote\"\tand ${PLAIN}"
+MULTI="first
+second"
+INTERP=${URFAVE_CLI_TEST_HOME}/app
+BARE=$PLAIN-suffix
+ESCAPED="\${PLAIN}"
+EMPTY=
+`))
+
+	r := require.New(t)
+	r.NoError(err)
+	r.Equal(map[string]string{
+		"PLAIN":    "value",
+		"SPACED":   "spaced value",
+		"HASH":     "a#b",
+		"EXPORTED": "yes",
+		"SINGLE":   `literal ${PLAIN} \n`,
+		"DOUBLE":   "escaped \"quote\"\tand value",
+		"MULTI":    "first\nsecond",
+		"INTERP":   "/home/test/app",
+		"BARE":     "value-suffix",
+		"ESCAPED":  "${PLAIN}",
+		"EMPTY":    "",
+	}, values)
+}
+
+func TestDotEnvParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		line int
+		msg  string
+	}{
+		{
+			name: "missing separator",
+			data: "A=1\nB\n",
+			line: 2,
+			msg:  `expected KEY=value, got "B"`,
+		},
+		{
+			name: "invalid key",
+			data: "\n\n1A=1\n",
+			line: 3,
+			msg:  `invalid key "1A"`,
+		},
+		{
+			name: "unterminated single quote",
+			data: "A=1\nB='open\nC=2\n",
+			line: 2,
+			msg:  "unterminated single-quoted value",
+		},
+		{
+			name: "unterminated double quote",
+			data: "A=\"open\n",
+			line: 1,
+			msg:  "unterminated double-quoted value",
+		},
+		{
+			name: "trailing characters",
+			data: "A=1\nB=\"x\" y\n",
+			line: 2,
+			msg:  `unexpected "y" after quoted value`,
+		},
+		{
+			name: "unterminated variable",
+			data: "A=${B\n",
+			line: 1,
+			msg:  `unterminated variable reference "${B"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseDotEnv("app.env", []byte(test.data))
+
+			r := require.New(t)
+			var perr *SourceParseError
+			r.ErrorAs(err, &perr)
+			r.Equal("app.env", perr.Path)
+			r.Equal(test.line, perr.Line)
+			r.Equal(test.msg, perr.Msg)
+			r.Equal(fmt.Sprintf("app.env:%[1]d: %[2]s", test.line, test.msg), err.Error())
+		})
+	}
+}
+
+func TestDotEnvValueSource(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, ".env.local")
+	shared := filepath.Join(dir, ".env")
+
+	r := require.New(t)
+	r.NoError(os.WriteFile(local, []byte("DB_HOST=localhost\n"), 0644))
+	r.NoError(os.WriteFile(shared, []byte("DB_HOST=db.example.com\nDB_PORT=5432\n"), 0644))
+
+	files := DotEnv(local, shared, filepath.Join(dir, "missing.env"))
+
+	t.Run("precedence", func(t *testing.T) {
+		r := require.New(t)
+
+		sources := files.EnvVars("DB_HOST")
+		str, src, ok := sources.LookupWithSource()
+		r.True(ok)
+		r.Equal("localhost", str)
+		r.Equal(fmt.Sprintf("key \"DB_HOST\" in dotenv file %[1]q", local), src.String())
+
+		sources = files.EnvVars("DB_PORT")
+		str, ok = sources.Lookup()
+		r.True(ok)
+		r.Equal("5432", str)
+
+		sources = files.EnvVars("DB_NAME")
+		_, ok = sources.Lookup()
+		r.False(ok)
+	})
+
+	t.Run("load", func(t *testing.T) {
+		values, err := files.Load()
+
+		r := require.New(t)
+		r.NoError(err)
+		r.Equal(map[string]string{"DB_HOST": "localhost", "DB_PORT": "5432"}, values)
+	})
+
+	t.Run("cache is refreshed on change", func(t *testing.T) {
+		r := require.New(t)
+		r.NoError(os.WriteFile(local, []byte("DB_HOST=127.0.0.1\nDB_USER=admin\n"), 0644))
+
+		sources := files.EnvVars("DB_USER")
+		str, ok := sources.Lookup()
+		r.True(ok)
+		r.Equal("admin", str)
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		r := require.New(t)
+		bad := filepath.Join(dir, "bad.env")
+		r.NoError(os.WriteFile(bad, []byte("OK=1\nNOT OK\n"), 0644))
+
+		sources := DotEnv(bad).EnvVars("OK")
+		_, ok := sources.Lookup()
+		r.False(ok)
+
+		_, err := DotEnv(bad).Load()
+		r.EqualError(err, bad+`:2: expected KEY=value, got "NOT OK"`)
+	})
+
+	t.Run("implements fmt.GoStringer", func(t *testing.T) {
+		src := &dotEnvValueSource{Path: ".env", Key: "FOO"}
+		require.Equal(t, "&dotEnvValueSource{Path:\".env\",Key:\"FOO\"}", src.GoString())
+	})
+}
+
+func TestINIParse(t *testing.T) {
+	values, err := parseINI("app.ini", []byte(`; global settings
+name = app
+[database]
+host = db.example.com ; inline comment
+port: 5432
+# another comment
+password = "p;ss # word"
+
+[server.tls]
+cert='/etc/cert.pem'
+`))
+
+	r := require.New(t)
+	r.NoError(err)
+	r.Equal(map[string]string{
+		"name":              "app",
+		"database.host":     "db.example.com",
+		"database.port":     "5432",
+		"database.password": "p;ss # word",
+		"server.tls.cert":   "/etc/cert.pem",
+	}, values)
+}
+
+func TestINIParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		line int
+		msg  string
+	}{
+		{
+			name: "unterminated section",
+			data: "a=1\n[db\n",
+			line: 2,
+			msg:  `unterminated section header "[db"`,
+		},
+		{
+			name: "empty section",
+			data: "[ ]\n",
+			line: 1,
+			msg:  "empty section name",
+		},
+		{
+			name: "missing separator",
+			data: "[db]\n\nhost\n",
+			line: 3,
+			msg:  `expected "key = value", got "host"`,
+		},
+		{
+			name: "missing key",
+			data: "= value\n",
+			line: 1,
+			msg:  `missing key in "= value"`,
+		},
+		{
+			name: "unterminated quote",
+			data: "[db]\nhost = \"open\n",
+			line: 2,
+			msg:  `unterminated quoted value for key "host"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseINI("app.ini", []byte(test.data))
+
+			r := require.New(t)
+			var perr *SourceParseError
+			r.ErrorAs(err, &perr)
+			r.Equal(test.line, perr.Line)
+			r.Equal(test.msg, perr.Msg)
+		})
+	}
+}
+
+func TestINIKey(t *testing.T) {
+	r := require.New(t)
+
+	fileName := filepath.Join(t.TempDir(), "app.ini")
+	r.NoError(os.WriteFile(fileName, []byte("[database]\nhost = db.example.com\n"), 0644))
+
+	sources := INIKey(fileName, "database.host")
+	str, src, ok := sources.LookupWithSource()
+	r.True(ok)
+	r.Equal("db.example.com", str)
+	r.Equal(fmt.Sprintf("key \"database.host\" in ini file %[1]q", fileName), src.String())
+	r.Equal(fmt.Sprintf("&iniValueSource{Path:%[1]q,Key:\"database.host\"}", fileName), src.GoString())
+
+	sources = INIKey(fileName, "database.port")
+	_, ok = sources.Lookup()
+	r.False(ok)
+
+	sources = INIKey(fileName+".missing", "database.host")
+	_, ok = sources.Lookup()
+	r.False(ok)
+}
+
+func TestJSONParse(t *testing.T) {
+	r := require.New(t)
+
+	values, err := parseJSON("config.json", []byte(`{
+  "host": "example.com",
+  "port": 8080,
+  "ratio": 0.5,
+  "debug": false,
+  "token": null,
+  "tags": ["a", "b"],
+  "labels": {"z": "1", "a": "2"},
+  "serve": {"port": 9090}
+}`))
+	r.NoError(err)
+	r.Equal(map[string]string{
+		"host":       "example.com",
+		"port":       "8080",
+		"ratio":      "0.5",
+		"debug":      "false",
+		"tags":       "a,b",
+		"labels":     "a=2,z=1",
+		"labels.a":   "2",
+		"labels.z":   "1",
+		"serve":      "port=9090",
+		"serve.port": "9090",
+	}, values)
+}
+
+func TestJSONParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		line int
+		msg  string
+	}{
+		{
+			name: "syntax error",
+			data: "{\n  \"a\": 1,\n  \"b\" 2\n}",
+			line: 3,
+			msg:  "invalid character '2' after object key",
+		},
+		{
+			name: "not an object",
+			data: "[1, 2]",
+			line: 1,
+			msg:  "json: cannot unmarshal array into Go value of type map[string]interface {}",
+		},
+		{
+			name: "trailing data",
+			data: "{}\n{}",
+			line: 2,
+			msg:  "unexpected data after top-level object",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseJSON("config.json", []byte(test.data))
+			require.EqualError(t, err, fmt.Sprintf("config.json:%[1]d: %[2]s", test.line, test.msg))
+		})
+	}
+}
+
+func TestJSONKey(t *testing.T) {
+	r := require.New(t)
+
+	fileName := filepath.Join(t.TempDir(), "config.json")
+	r.NoError(os.WriteFile(fileName, []byte(`{"database": {"host": "db.example.com"}}`), 0644))
+
+	sources := JSONKey(fileName, "database.host")
+	str, src, ok := sources.LookupWithSource()
+	r.True(ok)
+	r.Equal("db.example.com", str)
+	r.Equal(fmt.Sprintf("key \"database.host\" in json file %[1]q", fileName), src.String())
+	r.Equal(fmt.Sprintf("&jsonValueSource{Path:%[1]q,Key:\"database.host\"}", fileName), src.GoString())
+
+	sources = JSONKey(fileName, "database.port")
+	_, ok = sources.Lookup()
+	r.False(ok)
+
+	sources = JSONKey(fileName+".missing", "database.host")
+	_, ok = sources.Lookup()
+	r.False(ok)
+}
+
+func TestFSPath(t *testing.T) {
+	for in, out := range map[string]string{
+		"/etc/app/token": "etc/app/token",
+		"etc/app/token":  "etc/app/token",
+		"./a/../b":       "b",
+		"/":              ".",
+		"":               ".",
+	} {
+		require.Equal(t, out, fsPath(in), in)
+	}
+}
+
+func TestCommand_EnvAndFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/app/token":       {Data: []byte("file-token")},
+		"etc/app/.env":        {Data: []byte("DB_URL=postgres://${DB_HOST}/app\n")},
+		"etc/app/app.ini":     {Data: []byte("[serve]\nport = 8080\n")},
+		"etc/app/config.json": {Data: []byte(`{"name": "from-config", "serve": {"workers": 4}}`)},
+	}
+
+	var (
+		subEnv  string
+		subFile string
+	)
+
+	cmd := &Command{
+		Name:                "app",
+		Writer:              io.Discard,
+		ErrWriter:           io.Discard,
+		Env:                 map[string]string{"APP_USER": "alice", "DB_HOST": "db.internal"},
+		FS:                  fsys,
+		EnableConfigFile:    true,
+		EnableConfigCommand: true,
+		ConfigPaths:         []string{"/etc/app/config.json"},
+		Flags: []Flag{
+			&StringFlag{Name: "user", Sources: EnvVars("APP_USER")},
+			&StringFlag{Name: "token", Sources: Files("/etc/app/token")},
+			&StringFlag{Name: "db-url", Sources: DotEnv("/etc/app/.env").EnvVars("DB_URL")},
+			&StringFlag{Name: "name"},
+		},
+		Commands: []*Command{
+			{
+				Name: "serve",
+				Env:  map[string]string{"APP_USER": "bob"},
+				Flags: []Flag{
+					&StringFlag{Name: "user", Sources: EnvVars("APP_USER")},
+					&IntFlag{Name: "port", Sources: INIKey("/etc/app/app.ini", "serve.port")},
+					&IntFlag{Name: "workers"},
+				},
+				Action: func(_ context.Context, cmd *Command) error {
+					subEnv = cmd.String("user")
+					subFile = fmt.Sprint(cmd.Int("port"), "/", cmd.Int("workers"))
+					return nil
+				},
+			},
+		},
+	}
+
+	r := require.New(t)
+	r.NoError(cmd.Run(context.Background(), []string{"app", "serve"}))
+	r.Equal("alice", cmd.String("user"))
+	r.Equal("file-token", cmd.String("token"))
+	r.Equal("postgres://db.internal/app", cmd.String("db-url"))
+	r.Equal("from-config", cmd.String("name"))
+	r.Equal("bob", subEnv)
+	r.Equal("8080/4", subFile)
+
+	err := cmd.Run(context.Background(), []string{"app", "config", "set", "name", "x"})
+	r.EqualError(err, "config files read from a custom FS cannot be written")
+}
+
+func TestCommand_EnvUnknownEnvVars(t *testing.T) {
+	cmd := &Command{
+		Name:           "app",
+		Writer:         io.Discard,
+		ErrWriter:      io.Discard,
+		EnvPrefix:      "APP",
+		UnknownEnvVars: UnknownEnvVarsError,
+		Env:            map[string]string{"APP_USR": "alice"},
+		Flags:          []Flag{&StringFlag{Name: "user"}},
+	}
+
+	err := cmd.Run(context.Background(), []string{"app"})
+	require.ErrorContains(t, err, `"APP_USR"`)
+}